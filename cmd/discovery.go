@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	dynamicclient "github.com/tiny-systems/googleapis-module/components/dynamic-client"
+	"github.com/tiny-systems/googleapis-module/pkg/discovery"
+)
+
+var (
+	discoveryCacheDir string
+	discoveryOffline  bool
+	discoverySearch   string
+	discoveryAll      bool
+	syncOut           string
+	syncWorkers       int
+)
+
+var discoveryCmd = &cobra.Command{
+	Use:   "discovery",
+	Short: "list, search and inspect Google APIs",
+	Long: `Browse Google API discovery documents. Documents are cached on disk, so commands keep working offline.
+Cache directory defaults to $` + discovery.CacheDirEnv + ` or the user cache directory.`,
+}
+
+var discoveryServicesCmd = &cobra.Command{
+	Use:          "services",
+	Short:        "list available services",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		services, err := client.GetServices(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tTITLE\tPREFERRED")
+		for _, svc := range services {
+			if !discoveryAll && !svc.Preferred {
+				continue
+			}
+			if !matches(discoverySearch, svc.ID, svc.Title, svc.Description) {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%t\n", svc.ID, svc.Title, svc.Preferred)
+		}
+		return w.Flush()
+	},
+}
+
+var discoveryMethodsCmd = &cobra.Command{
+	Use:          "methods <service>",
+	Short:        "list methods of a service, e.g. calendar:v3",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		methods, err := client.GetMethods(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "METHOD\tHTTP\tPATH")
+		for _, m := range methods {
+			if !matches(discoverySearch, m.FullName, m.Method.Path, m.Method.Description) {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", m.FullName, m.Method.HttpMethod, m.Method.Path)
+		}
+		return w.Flush()
+	},
+}
+
+var discoveryDescribeCmd = &cobra.Command{
+	Use:          "describe <service> <method>",
+	Short:        "show parameters and generated request/response schemas of a method, e.g. calendar:v3 events.list",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		api, err := client.GetAPI(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		var method *googleapismodule.MethodInfo
+		for _, m := range api.GetAllMethods() {
+			if m.FullName == args[1] || m.Method.ID == args[1] {
+				method = &m
+				break
+			}
+		}
+		if method == nil {
+			return fmt.Errorf("method %s not found in %s", args[1], args[0])
+		}
+		return describeMethod(cmd.OutOrStdout(), api, *method)
+	},
+}
+
+var discoverySyncCmd = &cobra.Command{
+	Use:   "sync [service...]",
+	Short: "download discovery documents for air-gapped installs",
+	Long: `Download the discovery directory and documents of the given services (preferred versions of all services by default) into --out.
+Point ` + discovery.CacheDirEnv + ` of the module to that directory to use them offline.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newDiscoveryClient(syncOut)
+
+		if err := client.RefreshList(ctx); err != nil {
+			return err
+		}
+
+		serviceIDs := args
+		if len(serviceIDs) == 0 {
+			services, err := client.GetServices(ctx)
+			if err != nil {
+				return err
+			}
+			for _, svc := range services {
+				if discoveryAll || svc.Preferred {
+					serviceIDs = append(serviceIDs, svc.ID)
+				}
+			}
+		}
+
+		failed := syncServices(ctx, cmd.OutOrStdout(), client, serviceIDs)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d services failed to sync", failed, len(serviceIDs))
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "synced %d services to %s\n", len(serviceIDs), syncOut)
+		return nil
+	},
+}
+
+func init() {
+	discoveryCmd.PersistentFlags().StringVar(&discoveryCacheDir, "cache-dir", defaultDiscoveryCacheDir(), "discovery documents cache directory")
+	discoveryCmd.PersistentFlags().BoolVar(&discoveryOffline, "offline", false, "use cached documents only")
+
+	discoveryServicesCmd.Flags().StringVarP(&discoverySearch, "search", "s", "", "filter by text in ID, title or description")
+	discoveryServicesCmd.Flags().BoolVar(&discoveryAll, "all", false, "include non-preferred versions")
+	discoveryMethodsCmd.Flags().StringVarP(&discoverySearch, "search", "s", "", "filter by text in name, path or description")
+
+	discoverySyncCmd.Flags().StringVarP(&syncOut, "out", "o", "", "output directory")
+	discoverySyncCmd.Flags().BoolVar(&discoveryAll, "all", false, "include non-preferred versions")
+	discoverySyncCmd.Flags().IntVar(&syncWorkers, "workers", 8, "parallel downloads")
+	_ = discoverySyncCmd.MarkFlagRequired("out")
+
+	discoveryCmd.AddCommand(discoveryServicesCmd, discoveryMethodsCmd, discoveryDescribeCmd, discoverySyncCmd)
+}
+
+func newDiscoveryClient(cacheDir string) *discovery.Client {
+	return discovery.NewClient(discovery.WithCacheDir(cacheDir), discovery.WithOffline(discoveryOffline))
+}
+
+func defaultDiscoveryCacheDir() string {
+	if dir := os.Getenv(discovery.CacheDirEnv); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "googleapis-module", "discovery")
+	}
+	return ""
+}
+
+// matches reports whether any of fields contains the search text, case-insensitive
+func matches(search string, fields ...string) bool {
+	if search == "" {
+		return true
+	}
+	search = strings.ToLower(search)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncServices downloads documents in parallel and returns the number of failures
+func syncServices(ctx context.Context, out io.Writer, client *discovery.Client, serviceIDs []string) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	sem := make(chan struct{}, max(syncWorkers, 1))
+
+	for _, id := range serviceIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			_, err := client.RefreshAPI(ctx, id)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				_, _ = fmt.Fprintf(out, "%s: %v\n", id, err)
+				return
+			}
+			_, _ = fmt.Fprintf(out, "%s: ok\n", id)
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+func describeMethod(out io.Writer, api *googleapismodule.API, m googleapismodule.MethodInfo) error {
+	_, _ = fmt.Fprintf(out, "%s\n%s %s\n", m.Method.ID, m.Method.HttpMethod, m.Method.Path)
+	if m.Method.Description != "" {
+		_, _ = fmt.Fprintf(out, "\n%s\n", m.Method.Description)
+	}
+	if len(m.Method.Scopes) > 0 {
+		_, _ = fmt.Fprintf(out, "\nScopes:\n  %s\n", strings.Join(m.Method.Scopes, "\n  "))
+	}
+
+	if len(m.Method.Parameters) > 0 {
+		names := make([]string, 0, len(m.Method.Parameters))
+		for name := range m.Method.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		_, _ = fmt.Fprintln(out, "\nParameters:")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "  NAME\tLOCATION\tTYPE\tREQUIRED\tDESCRIPTION")
+		for _, name := range names {
+			p := m.Method.Parameters[name]
+			typ := p.Type
+			if p.Format != "" {
+				typ += "/" + p.Format
+			}
+			if p.Repeated {
+				typ = "[]" + typ
+			}
+			desc, _, _ := strings.Cut(p.Description, "\n")
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%t\t%s\n", name, p.Location, typ, p.Required, desc)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	converter := dynamicclient.NewSchemaConverter(api)
+	for _, s := range []struct {
+		title  string
+		schema dynamicclient.DynamicSchema
+	}{
+		{"Request", converter.BuildRequestSchema(m.Method)},
+		{"Response", converter.BuildResponseSchema(m.Method)},
+	} {
+		schema, err := s.schema.JSONSchema()
+		if err != nil {
+			return err
+		}
+		if err := printJSON(out, "\n"+s.title+" schema:", schema); err != nil {
+			return err
+		}
+		if err := printJSON(out, "\n"+s.title+" example:", s.schema.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSON(out io.Writer, title string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n%s\n", title, data)
+	return err
+}
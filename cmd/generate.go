@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tiny-systems/googleapis-module/pkg/codegen"
+)
+
+var generateOut string
+
+var discoveryGenerateCmd = &cobra.Command{
+	Use:   "generate <service> <method...>",
+	Short: "generate typed components for API methods, e.g. calendar:v3 events.list events.insert",
+	Long: `Generate static component packages which don't depend on discovery at runtime.
+Add blank imports of the printed packages to cmd/main.go to register them.`,
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		api, err := client.GetAPI(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		files, err := codegen.Generate(api, args[1:])
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			path := filepath.Join(generateOut, filepath.FromSlash(f.Path))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, f.Content, 0o644); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "wrote %s\n", path)
+		}
+
+		for _, method := range args[1:] {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", filepath.ToSlash(filepath.Join(generateOut, codegen.PackageDir(api, method))))
+		}
+		return nil
+	},
+}
+
+func init() {
+	discoveryGenerateCmd.Flags().StringVarP(&generateOut, "out", "o", "components", "output directory")
+
+	discoveryCmd.AddCommand(discoveryGenerateCmd)
+}
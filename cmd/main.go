@@ -10,8 +10,11 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	_ "github.com/tiny-systems/googleapis-module/components/auth/callback"
 	_ "github.com/tiny-systems/googleapis-module/components/auth/exchange-code"
 	_ "github.com/tiny-systems/googleapis-module/components/auth/get-url"
+	_ "github.com/tiny-systems/googleapis-module/components/auth/refresh-token"
+	_ "github.com/tiny-systems/googleapis-module/components/auth/verify-id-token"
 	_ "github.com/tiny-systems/googleapis-module/components/calendar/channel-stop"
 	_ "github.com/tiny-systems/googleapis-module/components/calendar/channel-watch"
 	_ "github.com/tiny-systems/googleapis-module/components/calendar/get-calendars"
@@ -24,6 +27,7 @@ import (
 	_ "github.com/tiny-systems/googleapis-module/components/firestore/listen-collection"
 	_ "github.com/tiny-systems/googleapis-module/components/firestore/update-doc"
 	_ "github.com/tiny-systems/googleapis-module/components/firestore/update-doc-field"
+	"github.com/tiny-systems/googleapis-module/pkg/clientpool"
 	"github.com/tiny-systems/module/cli"
 )
 
@@ -48,7 +52,10 @@ func main() {
 	defer stop()
 
 	cli.RegisterCommands(rootCmd)
+	rootCmd.AddCommand(discoveryCmd)
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Printf("command execute error: %v\n", err)
 	}
+	// close pooled Google clients and their connections
+	_ = clientpool.CloseAll()
 }
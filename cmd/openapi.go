@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tiny-systems/googleapis-module/pkg/openapi"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	openapiOut    string
+	openapiFormat string
+)
+
+var discoveryOpenAPICmd = &cobra.Command{
+	Use:          "openapi <service>",
+	Short:        "export a discovery document as OpenAPI 3.1, e.g. calendar:v3",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		api, err := client.GetAPI(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(openapi.Convert(api), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		switch openapiFormat {
+		case "json":
+		case "yaml":
+			if data, err = yaml.JSONToYAML(data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown format %s, use json or yaml", openapiFormat)
+		}
+
+		if openapiOut == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		return os.WriteFile(openapiOut, data, 0o644)
+	},
+}
+
+func init() {
+	discoveryOpenAPICmd.Flags().StringVarP(&openapiOut, "out", "o", "", "output file, stdout by default")
+	discoveryOpenAPICmd.Flags().StringVarP(&openapiFormat, "format", "f", "json", "output format: json or yaml")
+
+	discoveryCmd.AddCommand(discoveryOpenAPICmd)
+}
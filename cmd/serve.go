@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/pkg/discovery"
+	"github.com/tiny-systems/googleapis-module/pkg/fakeserver"
+)
+
+var (
+	serveAddr     string
+	serveFixtures string
+)
+
+var discoveryServeCmd = &cobra.Command{
+	Use:   "serve <service...>",
+	Short: "run a fake API server for offline flow testing, e.g. calendar:v3",
+	Long: `Serve discovery documents of the given services and fake endpoints generated from them.
+Requests are validated against the schemas and answered with schema-shaped examples, or with fixtures
+from --fixtures, a JSON object of method IDs to lists of {"status", "header", "body"} answered in order.
+Point ` + discovery.ListURLEnv + ` of the module to http://<addr>` + fakeserver.ListPath + ` to use it.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newDiscoveryClient(discoveryCacheDir)
+
+		apis := make([]*googleapismodule.API, 0, len(args))
+		for _, id := range args {
+			api, err := client.GetAPI(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			apis = append(apis, api)
+		}
+
+		server := fakeserver.New(apis...)
+		if serveFixtures != "" {
+			data, err := os.ReadFile(serveFixtures)
+			if err != nil {
+				return err
+			}
+			var fixtures map[string][]fakeserver.Fixture
+			if err := json.Unmarshal(data, &fixtures); err != nil {
+				return fmt.Errorf("invalid fixtures file: %w", err)
+			}
+			for methodID, f := range fixtures {
+				server.Stub(methodID, f...)
+			}
+		}
+
+		server.OnCall = func(c fakeserver.Call) {
+			if c.Err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s %s %d: %v\n", c.MethodID, c.HTTPMethod, c.Path, c.Status, c.Err)
+				return
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s %s %d\n", c.MethodID, c.HTTPMethod, c.Path, c.Status)
+		}
+
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "serving %d services on %s, discovery list at %s\n", len(apis), serveAddr, fakeserver.ListPath)
+		return http.ListenAndServe(serveAddr, server)
+	},
+}
+
+func init() {
+	discoveryServeCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8085", "listen address")
+	discoveryServeCmd.Flags().StringVar(&serveFixtures, "fixtures", "", "JSON file with scripted responses by method ID")
+
+	discoveryCmd.AddCommand(discoveryServeCmd)
+}
@@ -0,0 +1,429 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/module/api/v1alpha1"
+	"github.com/tiny-systems/module/module"
+	"github.com/tiny-systems/module/registry"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ComponentName = "oauth_callback"
+	StartPort     = "start"
+	StopPort      = "stop"
+	ResponsePort  = "response"
+	DeniedPort    = "denied"
+	ErrorPort     = "error"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type Context any
+
+type StartControl struct {
+	Status string `json:"status" title:"Status" readonly:"true"`
+}
+
+type StopControl struct {
+	Stop   bool   `json:"stop" format:"button" title:"Stop" required:"true" description:"Stop receiving callbacks"`
+	Status string `json:"status" title:"Status" readonly:"true"`
+}
+
+type Stop struct {
+}
+
+type Settings struct {
+	EnableErrorPort bool   `json:"enableErrorPort" required:"true" title:"Enable error port" description:"If request may fail, error port will emit an error message"`
+	EnableStopPort  bool   `json:"enableStopPort" required:"true" title:"Enable stop port" description:"Stop port allows you to stop the receiver"`
+	VerifyState     bool   `json:"verifyState" title:"Verify State" default:"true" description:"Reject callbacks whose state was not signed by the auth URL component for the same session or has expired, protecting against CSRF"`
+	SessionCookie   string `json:"sessionCookie,omitempty" title:"Session Cookie" default:"session" description:"Cookie holding the session ID given to the auth URL component, required to verify the state"`
+	StateSecret     string `json:"stateSecret,omitempty" title:"State Secret" description:"Key the state was signed with, must match the auth URL component. Derived from the client secret if empty"`
+}
+
+type Start struct {
+	Context     Context          `json:"context,omitempty" title:"Context" configurable:"true"`
+	Config      etc.ClientConfig `json:"config" title:"Config" required:"true" description:"Client Config"`
+	ListenAddr  string           `json:"listenAddr" title:"Listen Address" required:"true" default:":8080" description:"Address the redirect endpoint is served on"`
+	Path        string           `json:"path" title:"Path" required:"true" default:"/oauth2/callback" description:"Path of the redirect URL"`
+	RedirectURL string           `json:"redirectUrl,omitempty" title:"Redirect URL" description:"Public redirect URL registered with Google, overrides redirect URL from config"`
+	SuccessURL  string           `json:"successUrl,omitempty" title:"Success URL" description:"Page the browser is sent to after signing in. A plain confirmation is shown if empty"`
+	DeniedURL   string           `json:"deniedUrl,omitempty" title:"Denied URL" description:"Page the browser is sent to if the user denied access. A plain message is shown if empty"`
+}
+
+type Response struct {
+	Context Context   `json:"context" title:"Context"`
+	Token   etc.Token `json:"token"`
+	IDToken string    `json:"idToken,omitempty" title:"ID Token" description:"OpenID Connect ID token identifying the user, present if the openid scope was granted"`
+	State   string    `json:"state,omitempty" title:"State" description:"Value carried in the state"`
+	Scopes  []string  `json:"scopes,omitempty" title:"Scopes" description:"Scopes granted by the user"`
+}
+
+// Denied is emitted when the user declined the consent screen or Google reported an error
+type Denied struct {
+	Context          Context `json:"context" title:"Context"`
+	Error            string  `json:"error" title:"Error" description:"OAuth error code, e.g. access_denied"`
+	ErrorDescription string  `json:"errorDescription,omitempty" title:"Error Description"`
+	State            string  `json:"state,omitempty" title:"State" description:"Value carried in the state"`
+}
+
+type Error struct {
+	Context Context `json:"context"`
+	Error   string  `json:"error"`
+}
+
+type Component struct {
+	settings     Settings
+	settingsLock *sync.RWMutex
+
+	startSettings  Start
+	cancelFunc     context.CancelFunc
+	cancelFuncLock *sync.Mutex
+
+	runLock *sync.Mutex
+}
+
+func (g *Component) GetInfo() module.ComponentInfo {
+	return module.ComponentInfo{
+		Name:        ComponentName,
+		Description: "OAuth Callback",
+		Info:        "Serves the OAuth redirect endpoint. Verifies the state, exchanges the code and emits the token, or the error if the user denied access",
+		Tags:        []string{"google", "auth"},
+	}
+}
+
+// OnSettings stores the component settings.
+func (g *Component) OnSettings(_ context.Context, msg any) error {
+	in, ok := msg.(Settings)
+	if !ok {
+		return fmt.Errorf("invalid settings")
+	}
+	if err := in.validate(); err != nil {
+		return err
+	}
+	g.settingsLock.Lock()
+	defer g.settingsLock.Unlock()
+	g.settings = in
+	return nil
+}
+
+// validate checks the settings, verifying the state needs the session of the browser
+func (s Settings) validate() error {
+	if s.VerifyState && s.SessionCookie == "" {
+		return fmt.Errorf("session cookie is required to verify the state")
+	}
+	return nil
+}
+
+// getSettings returns a snapshot of the settings, callbacks read them from server goroutines
+func (g *Component) getSettings() Settings {
+	g.settingsLock.RLock()
+	defer g.settingsLock.RUnlock()
+	return g.settings
+}
+
+// OnControl handles the Stop button on the dashboard.
+func (g *Component) OnControl(_ context.Context, msg any) error {
+	if msg == nil {
+		return nil
+	}
+	if _, ok := msg.(StopControl); ok {
+		return g.stop()
+	}
+	return nil
+}
+
+// Handle dispatches the StartPort and StopPort. System ports go through capabilities.
+func (g *Component) Handle(ctx context.Context, handler module.Handler, port string, msg interface{}) module.Result {
+	switch port {
+	case StartPort:
+		req, ok := msg.(Start)
+		if !ok {
+			return module.Fail(fmt.Errorf("invalid request"))
+		}
+		g.startSettings = req
+		return module.Fail(g.start(ctx, handler))
+	case StopPort:
+		return module.Fail(g.stop())
+	}
+	return module.Fail(fmt.Errorf("invalid port"))
+}
+
+func (g *Component) start(ctx context.Context, handler module.Handler) error {
+
+	g.runLock.Lock()
+	defer g.runLock.Unlock()
+
+	listenCtx, listenCancel := context.WithCancel(ctx)
+	defer listenCancel()
+
+	start := g.startSettings
+	path := start.Path
+	if path == "" {
+		path = "/oauth2/callback"
+	}
+
+	listener, err := net.Listen("tcp", start.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		// callbacks aren't children of the start message
+		g.serveCallback(trace.ContextWithSpanContext(listenCtx, trace.NewSpanContext(trace.SpanContextConfig{})), handler, start, w, r)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	g.setCancelFunc(listenCancel)
+	// reconcile so show we are listening
+	_ = handler(context.Background(), v1alpha1.ReconcilePort, nil)
+
+	defer func() {
+		g.setCancelFunc(nil)
+		_ = handler(context.Background(), v1alpha1.ReconcilePort, nil)
+	}()
+
+	go func() {
+		<-listenCtx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveCallback handles a redirect from Google's consent screen
+func (g *Component) serveCallback(ctx context.Context, handler module.Handler, start Start, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	state, err := g.verifyState(start, query.Get("state"), r)
+	if err != nil {
+		g.fail(ctx, handler, start, w, http.StatusBadRequest, err)
+		return
+	}
+
+	if oauthErr := query.Get("error"); oauthErr != "" {
+		result := handler(ctx, DeniedPort, Denied{
+			Context:          start.Context,
+			Error:            oauthErr,
+			ErrorDescription: query.Get("error_description"),
+			State:            state.Value,
+		})
+		if result.IsErr() {
+			writePage(w, http.StatusInternalServerError, "Sign-in failed", "Please try again.")
+			return
+		}
+		if start.DeniedURL != "" {
+			http.Redirect(w, r, start.DeniedURL, http.StatusFound)
+			return
+		}
+		writePage(w, http.StatusOK, "Sign-in cancelled", "Access was not granted, you can close this window.")
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		g.fail(ctx, handler, start, w, http.StatusBadRequest, fmt.Errorf("callback has no code"))
+		return
+	}
+
+	token, idToken, err := etc.ExchangeCode(ctx, start.Config, code, start.RedirectURL, state.Verifier)
+	if err != nil {
+		g.fail(ctx, handler, start, w, http.StatusBadGateway, err)
+		return
+	}
+
+	result := handler(ctx, ResponsePort, Response{
+		Context: start.Context,
+		Token:   *token,
+		IDToken: idToken,
+		State:   state.Value,
+		Scopes:  strings.Fields(query.Get("scope")),
+	})
+	if result.IsErr() {
+		writePage(w, http.StatusInternalServerError, "Sign-in failed", "Please try again.")
+		return
+	}
+	if start.SuccessURL != "" {
+		http.Redirect(w, r, start.SuccessURL, http.StatusFound)
+		return
+	}
+	writePage(w, http.StatusOK, "Signed in", "You can close this window.")
+}
+
+// verifyState returns the content of a signed state, which must be bound to the session of the browser
+func (g *Component) verifyState(start Start, state string, r *http.Request) (etc.State, error) {
+	settings := g.getSettings()
+	if !settings.VerifyState {
+		return etc.State{}, nil
+	}
+	if err := settings.validate(); err != nil {
+		return etc.State{}, err
+	}
+	cookie, err := r.Cookie(settings.SessionCookie)
+	if err != nil || cookie.Value == "" {
+		return etc.State{}, fmt.Errorf("%w: session cookie %s is missing", etc.ErrInvalidState, settings.SessionCookie)
+	}
+	key, err := etc.StateKey(start.Config, settings.StateSecret)
+	if err != nil {
+		return etc.State{}, err
+	}
+	return etc.VerifyState(key, state, cookie.Value)
+}
+
+// fail answers the browser and reports the error, details are not shown to the user
+func (g *Component) fail(ctx context.Context, handler module.Handler, start Start, w http.ResponseWriter, status int, err error) {
+	writePage(w, status, "Sign-in failed", "Please try again.")
+	// check err port
+	if !g.getSettings().EnableErrorPort {
+		return
+	}
+	_ = handler(ctx, ErrorPort, Error{
+		Context: start.Context,
+		Error:   err.Error(),
+	})
+}
+
+func writePage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%[1]s</title></head><body><h1>%[1]s</h1><p>%[2]s</p></body></html>",
+		html.EscapeString(title), html.EscapeString(message))
+}
+
+func (g *Component) stop() error {
+	g.cancelFuncLock.Lock()
+	defer g.cancelFuncLock.Unlock()
+	if g.cancelFunc == nil {
+		return nil
+	}
+	g.cancelFunc()
+
+	return nil
+}
+
+func (g *Component) setCancelFunc(f func()) {
+	g.cancelFuncLock.Lock()
+	defer g.cancelFuncLock.Unlock()
+	g.cancelFunc = f
+}
+
+func (g *Component) isListening() bool {
+	g.cancelFuncLock.Lock()
+	defer g.cancelFuncLock.Unlock()
+
+	return g.cancelFunc != nil
+}
+
+func (g *Component) getControl() interface{} {
+	if g.isListening() {
+		return StopControl{
+			Status: "Listening",
+		}
+	}
+	return StartControl{
+		Status: "Not listening",
+	}
+}
+
+func (g *Component) Ports() []module.Port {
+	settings := g.getSettings()
+	ports := []module.Port{
+		{
+			Name:          v1alpha1.SettingsPort,
+			Label:         "Settings",
+			Configuration: Settings{VerifyState: true, SessionCookie: "session"},
+		},
+		{
+			Name:          StartPort,
+			Label:         "Start",
+			Position:      module.Left,
+			Configuration: g.startSettings,
+		},
+		{
+			Name:          v1alpha1.ControlPort,
+			Label:         "Dashboard",
+			Source:        true,
+			Configuration: g.getControl(),
+		},
+		{
+			Source:        true,
+			Name:          ResponsePort,
+			Label:         "Response",
+			Position:      module.Right,
+			Configuration: Response{},
+		},
+		{
+			Source:        true,
+			Name:          DeniedPort,
+			Label:         "Denied",
+			Position:      module.Right,
+			Configuration: Denied{},
+		},
+	}
+
+	// programmatically stop server
+	if settings.EnableStopPort {
+		ports = append(ports, module.Port{
+			Position:      module.Left,
+			Name:          StopPort,
+			Label:         "Stop",
+			Configuration: Stop{},
+		})
+	}
+
+	if !settings.EnableErrorPort {
+		return ports
+	}
+
+	return append(ports, module.Port{
+		Position:      module.Bottom,
+		Name:          ErrorPort,
+		Label:         "Error",
+		Source:        true,
+		Configuration: Error{},
+	})
+}
+
+func (g *Component) Instance() module.Component {
+	return &Component{
+		settings:       Settings{VerifyState: true, SessionCookie: "session"},
+		settingsLock:   &sync.RWMutex{},
+		cancelFuncLock: &sync.Mutex{},
+		runLock:        &sync.Mutex{},
+		startSettings: Start{
+			ListenAddr: ":8080",
+			Path:       "/oauth2/callback",
+		},
+	}
+}
+
+var (
+	_ module.Component       = (*Component)(nil)
+	_ module.SettingsHandler = (*Component)(nil)
+	_ module.ControlHandler  = (*Component)(nil)
+)
+
+func init() {
+	registry.Register(&Component{
+		settingsLock:   &sync.RWMutex{},
+		cancelFuncLock: &sync.Mutex{},
+		runLock:        &sync.Mutex{},
+	})
+}
@@ -0,0 +1,181 @@
+package callback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/module/module"
+)
+
+// emitted is a message sent to an output port
+type emitted struct {
+	port string
+	data any
+}
+
+func TestServeCallback(t *testing.T) {
+	// token endpoint of the client config, checks the PKCE verifier carried in the state
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != "code" || r.PostForm.Get("code_verifier") != "verifier" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	config := etc.ClientConfig{Credentials: `{"web":{"client_id":"id","client_secret":"secret","auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"` + tokenServer.URL + `","redirect_uris":["https://example.com/oauth2/callback"]}}`}
+	key, err := etc.StateKey(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := etc.SignState(key, etc.State{Value: "/return", Verifier: "verifier", Session: "browser"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := (&Component{}).Instance().(*Component).settings
+	defaults.EnableErrorPort = true
+
+	tests := []struct {
+		name     string
+		settings Settings
+		query    url.Values
+		cookie   *http.Cookie
+		status   int
+		port     string
+		error    string
+	}{
+		{
+			name:   "signed in",
+			query:  url.Values{"code": {"code"}, "state": {state}, "scope": {"openid email"}},
+			cookie: &http.Cookie{Name: "session", Value: "browser"},
+			status: http.StatusOK,
+			port:   ResponsePort,
+		},
+		{
+			name:   "denied",
+			query:  url.Values{"error": {"access_denied"}, "state": {state}},
+			cookie: &http.Cookie{Name: "session", Value: "browser"},
+			status: http.StatusOK,
+			port:   DeniedPort,
+		},
+		{
+			name:   "no session cookie",
+			query:  url.Values{"code": {"code"}, "state": {state}},
+			status: http.StatusBadRequest,
+			port:   ErrorPort,
+			error:  "session cookie session is missing",
+		},
+		{
+			name:   "other session",
+			query:  url.Values{"code": {"code"}, "state": {state}},
+			cookie: &http.Cookie{Name: "session", Value: "attacker"},
+			status: http.StatusBadRequest,
+			port:   ErrorPort,
+			error:  "issued to another session",
+		},
+		{
+			name:   "forged state",
+			query:  url.Values{"code": {"code"}, "state": {"forged"}},
+			cookie: &http.Cookie{Name: "session", Value: "browser"},
+			status: http.StatusBadRequest,
+			port:   ErrorPort,
+		},
+		{
+			name:     "custom cookie",
+			settings: Settings{EnableErrorPort: true, VerifyState: true, SessionCookie: "sid"},
+			query:    url.Values{"code": {"code"}, "state": {state}},
+			cookie:   &http.Cookie{Name: "session", Value: "browser"},
+			status:   http.StatusBadRequest,
+			port:     ErrorPort,
+			error:    "session cookie sid is missing",
+		},
+		{
+			name:   "no code",
+			query:  url.Values{"state": {state}},
+			cookie: &http.Cookie{Name: "session", Value: "browser"},
+			status: http.StatusBadRequest,
+			port:   ErrorPort,
+			error:  "no code",
+		},
+		{
+			name:     "verification disabled",
+			settings: Settings{EnableErrorPort: true},
+			query:    url.Values{"code": {"code"}, "state": {"unsigned"}},
+			status:   http.StatusBadGateway,
+			port:     ErrorPort,
+			error:    "invalid_grant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := tt.settings
+			if settings == (Settings{}) {
+				settings = defaults
+			}
+			g := &Component{settings: settings, settingsLock: &sync.RWMutex{}}
+
+			var got []emitted
+			handler := func(_ context.Context, port string, data any) module.Result {
+				got = append(got, emitted{port: port, data: data})
+				return module.Ok(nil)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/oauth2/callback?"+tt.query.Encode(), nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			g.serveCallback(context.Background(), handler, Start{Context: "ctx", Config: config}, w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if len(got) != 1 || got[0].port != tt.port {
+				t.Fatalf("emitted %+v, want one message to %s", got, tt.port)
+			}
+
+			switch msg := got[0].data.(type) {
+			case Response:
+				if msg.Token.AccessToken != "access" || msg.State != "/return" || msg.Context != "ctx" || len(msg.Scopes) != 2 {
+					t.Fatalf("unexpected response %+v", msg)
+				}
+			case Denied:
+				if msg.Error != "access_denied" || msg.State != "/return" {
+					t.Fatalf("unexpected denial %+v", msg)
+				}
+			case Error:
+				if !strings.Contains(msg.Error, tt.error) {
+					t.Fatalf("error %q doesn't contain %q", msg.Error, tt.error)
+				}
+			}
+		})
+	}
+}
+
+func TestOnSettingsRequiresSessionCookie(t *testing.T) {
+	g := (&Component{}).Instance().(*Component)
+	if err := g.OnSettings(context.Background(), Settings{VerifyState: true}); err == nil {
+		t.Fatal("expected an error without session cookie")
+	}
+	if err := g.OnSettings(context.Background(), Settings{}); err != nil {
+		t.Fatalf("unexpected error without state verification: %v", err)
+	}
+	if err := g.OnSettings(context.Background(), Settings{VerifyState: true, SessionCookie: "sid"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := g.Instance().(*Component).getSettings().validate(); err != nil {
+		t.Fatalf("default settings are invalid: %v", err)
+	}
+}
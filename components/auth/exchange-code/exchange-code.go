@@ -7,8 +7,6 @@ import (
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 const (
@@ -21,19 +19,26 @@ const (
 type Context any
 
 type Request struct {
-	Context     Context          `json:"context,omitempty" title:"Context" configurable:"true"`
-	Config      etc.ClientConfig `json:"config" title:"Config"  required:"true" description:"Client Config"`
-	AuthCode    string           `json:"authCode" required:"true" title:"Authorisation code"`
-	RedirectURL string           `json:"redirectUrl" title:"Redirect URL" description:"Overrides redirect URL from config"`
+	Context      Context          `json:"context,omitempty" title:"Context" configurable:"true"`
+	Config       etc.ClientConfig `json:"config" title:"Config"  required:"true" description:"Client Config"`
+	AuthCode     string           `json:"authCode" required:"true" title:"Authorisation code"`
+	RedirectURL  string           `json:"redirectUrl" title:"Redirect URL" description:"Overrides redirect URL from config"`
+	State        string           `json:"state,omitempty" title:"State" description:"State parameter of the callback"`
+	Session      string           `json:"session,omitempty" title:"Session" description:"ID of the browser session the callback arrived in, must equal the session given to the auth URL if it got one"`
+	CodeVerifier string           `json:"codeVerifier,omitempty" title:"Code Verifier" description:"PKCE verifier emitted with the auth URL, taken from a verified state if empty"`
 }
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort bool   `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	VerifyState     bool   `json:"verifyState" title:"Verify State" description:"Reject callbacks whose state was not signed by the auth URL component or has expired, protecting against CSRF. States bound to a session must arrive with the same session"`
+	StateSecret     string `json:"stateSecret,omitempty" title:"State Secret" description:"Key the state was signed with, must match the auth URL component. Derived from the client secret if empty"`
 }
 
 type Response struct {
 	Context Context   `json:"context" title:"Context"`
 	Token   etc.Token `json:"token"`
+	IDToken string    `json:"idToken,omitempty" title:"ID Token" description:"OpenID Connect ID token identifying the user, present if the openid scope was granted"`
+	State   string    `json:"state,omitempty" title:"State" description:"Value carried in the verified state"`
 }
 
 type Error struct {
@@ -56,17 +61,25 @@ func (a *Component) GetInfo() module.ComponentInfo {
 	}
 }
 
-func (a *Component) exchange(ctx context.Context, in Request) (*oauth2.Token, error) {
-
-	config, err := google.ConfigFromJSON([]byte(in.Config.Credentials), in.Config.Scopes...)
+// verifyState returns the content of a signed state
+func (a *Component) verifyState(in Request) (etc.State, error) {
+	if !a.settings.VerifyState {
+		return etc.State{}, nil
+	}
+	key, err := etc.StateKey(in.Config, a.settings.StateSecret)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
+		return etc.State{}, err
 	}
+	return etc.VerifyState(key, in.State, in.Session)
+}
 
-	if in.RedirectURL != "" {
-		config.RedirectURL = in.RedirectURL
+func (a *Component) exchange(ctx context.Context, in Request, state etc.State) (*etc.Token, string, error) {
+	verifier := in.CodeVerifier
+	if verifier == "" {
+		// embedded in the state by the auth URL component
+		verifier = state.Verifier
 	}
-	return config.Exchange(ctx, in.AuthCode)
+	return etc.ExchangeCode(ctx, in.Config, in.AuthCode, in.RedirectURL, verifier)
 }
 
 // OnSettings stores the component settings.
@@ -90,7 +103,19 @@ func (a *Component) Handle(ctx context.Context, output module.Handler, port stri
 		return module.Fail(fmt.Errorf("invalid input message"))
 	}
 
-	token, err := a.exchange(ctx, in)
+	state, err := a.verifyState(in)
+	if err != nil {
+		// check err port
+		if !a.settings.EnableErrorPort {
+			return module.Fail(err)
+		}
+		return output(ctx, ErrorPort, Error{
+			Context: in.Context,
+			Error:   err.Error(),
+		})
+	}
+
+	token, idToken, err := a.exchange(ctx, in, state)
 	if err != nil {
 		// check err port
 		if !a.settings.EnableErrorPort {
@@ -104,12 +129,9 @@ func (a *Component) Handle(ctx context.Context, output module.Handler, port stri
 
 	return output(ctx, ResponsePort, Response{
 		Context: in.Context,
-		Token: etc.Token{
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-			TokenType:    token.TokenType,
-			Expiry:       token.Expiry,
-		},
+		Token:   *token,
+		IDToken: idToken,
+		State:   state.Value,
 	})
 
 
@@ -0,0 +1,80 @@
+package exchange_code
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+)
+
+func TestVerifyState(t *testing.T) {
+	config := etc.ClientConfig{Credentials: `{"web":{"client_id":"id","client_secret":"secret"}}`}
+	key, err := etc.StateKey(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(state etc.State) string {
+		s, err := etc.SignState(key, state, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		in       Request
+		want     etc.State
+		err      bool
+	}{
+		{name: "bare code by default", settings: (&Component{}).Instance().(*Component).settings, in: Request{Config: config}},
+		{name: "forged state ignored when off", in: Request{Config: config, State: "forged"}},
+		{name: "forged state", settings: Settings{VerifyState: true}, in: Request{Config: config, State: "forged"}, err: true},
+		{name: "missing state", settings: Settings{VerifyState: true}, in: Request{Config: config}, err: true},
+		{
+			name:     "state without session",
+			settings: Settings{VerifyState: true},
+			in:       Request{Config: config, State: sign(etc.State{Value: "/return", Verifier: "verifier"})},
+			want:     etc.State{Value: "/return", Verifier: "verifier"},
+		},
+		{
+			name:     "state with session",
+			settings: Settings{VerifyState: true},
+			in:       Request{Config: config, State: sign(etc.State{Value: "/return", Session: "browser"}), Session: "browser"},
+			want:     etc.State{Value: "/return", Session: "browser"},
+		},
+		{
+			name:     "state of another session",
+			settings: Settings{VerifyState: true},
+			in:       Request{Config: config, State: sign(etc.State{Session: "browser"}), Session: "attacker"},
+			err:      true,
+		},
+		{
+			name:     "other state secret",
+			settings: Settings{VerifyState: true, StateSecret: "other"},
+			in:       Request{Config: config, State: sign(etc.State{})},
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Component{settings: tt.settings}
+			got, err := a.verifyState(tt.in)
+			if tt.err {
+				if !errors.Is(err, etc.ErrInvalidState) {
+					t.Fatalf("expected ErrInvalidState, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package get_url
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/tiny-systems/googleapis-module/components/etc"
 	"github.com/tiny-systems/module/api/v1alpha1"
@@ -22,14 +25,26 @@ const (
 type Context any
 
 type Request struct {
-	Context       Context          `json:"context,omitempty" title:"Context" configurable:"true"`
-	Config        etc.ClientConfig `json:"config" required:"true" title:"Client credentials"`
-	AccessType    string           `json:"accessType" title:"Type of access" enum:"offline,online" enumTitles:"Offline,Online" required:"true"`
-	ApprovalForce bool             `json:"approvalForce" title:"ApprovalForce" required:"true"`
+	Context              Context          `json:"context,omitempty" title:"Context" configurable:"true"`
+	Config               etc.ClientConfig `json:"config" required:"true" title:"Client credentials"`
+	AccessType           string           `json:"accessType" title:"Type of access" enum:"offline,online" enumTitles:"Offline,Online" required:"true"`
+	ApprovalForce        bool             `json:"approvalForce" title:"ApprovalForce" required:"true"`
+	PKCE                 bool             `json:"pkce" title:"PKCE" description:"Bind the code to a S256 challenge, pass the emitted code verifier to the code exchange"`
+	State                string           `json:"state,omitempty" title:"State" description:"Value carried through the sign-in, e.g. a return path. It is signed with a random nonce and an expiry, so the code exchange can verify the callback"`
+	Session              string           `json:"session,omitempty" title:"Session" description:"ID of the browser session starting the sign-in, e.g. a random cookie value. The state is bound to it and the callback is only accepted from the same session"`
+	Scopes               []string         `json:"scopes,omitempty" title:"Scopes" description:"Overrides scopes from config, e.g. to request additional scopes for incremental authorization"`
+	IncludeGrantedScopes bool             `json:"includeGrantedScopes" title:"Include Granted Scopes" description:"Token also covers scopes the user granted before"`
+	Prompt               string           `json:"prompt,omitempty" title:"Prompt" description:"Space separated list of consent, select_account or none. ApprovalForce adds consent"`
+	LoginHint            string           `json:"loginHint,omitempty" title:"Login Hint" description:"Email or sub identifier of the user to sign in"`
+	HostedDomain         string           `json:"hostedDomain,omitempty" title:"Hosted Domain" description:"Google Workspace domain to restrict the account chooser to. Verify the hd claim of the ID token as well, users can remove the parameter"`
+	RedirectURL          string           `json:"redirectUrl,omitempty" title:"Redirect URL" description:"Overrides redirect URL from config"`
 }
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort bool   `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	StateSecret     string `json:"stateSecret,omitempty" title:"State Secret" description:"Key signing the state, must match the code exchange. Derived from the client secret if empty"`
+	StateTTL        int    `json:"stateTtl,omitempty" title:"State TTL" default:"600" minimum:"1" description:"Seconds the sign-in may take before the state expires"`
+	EmbedVerifier   bool   `json:"embedVerifier" title:"Embed Verifier" description:"Carry the PKCE code verifier encrypted in the state, so the code exchange or OAuth callback can use it without storing it"`
 }
 
 type Error struct {
@@ -38,8 +53,10 @@ type Error struct {
 }
 
 type Response struct {
-	Context Context `json:"context"`
-	AuthUrl string  `json:"authUrl" format:"uri"`
+	Context      Context `json:"context"`
+	AuthUrl      string  `json:"authUrl" format:"uri"`
+	State        string  `json:"state" title:"State" description:"Signed state sent with the URL"`
+	CodeVerifier string  `json:"codeVerifier,omitempty" title:"Code Verifier" description:"PKCE secret to keep server-side and pass to the code exchange"`
 }
 
 type Component struct {
@@ -75,7 +92,7 @@ func (a *Component) Handle(ctx context.Context, output module.Handler, port stri
 	if !ok {
 		return module.Fail(fmt.Errorf("invalid input message"))
 	}
-	url, err := getAuthUrl(ctx, in)
+	resp, err := a.getAuthUrl(ctx, in)
 
 	if err != nil {
 		// check err port
@@ -88,29 +105,88 @@ func (a *Component) Handle(ctx context.Context, output module.Handler, port stri
 		})
 	}
 
-	return output(ctx, ResponsePort, Response{
-		Context: in.Context,
-		AuthUrl: url,
-	})
+	resp.Context = in.Context
+	return output(ctx, ResponsePort, resp)
 
 }
 
-func getAuthUrl(_ context.Context, in Request) (string, error) {
+func (a *Component) getAuthUrl(_ context.Context, in Request) (Response, error) {
 
-	config, err := google.ConfigFromJSON([]byte(in.Config.Credentials), in.Config.Scopes...)
+	scopes := in.Config.Scopes
+	if len(in.Scopes) > 0 {
+		scopes = in.Scopes
+	}
+	config, err := google.ConfigFromJSON([]byte(in.Config.Credentials), scopes...)
 	if err != nil {
-		return "", fmt.Errorf("unable to parse client secret file to config: %v", err)
+		return Response{}, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
+	if in.RedirectURL != "" {
+		config.RedirectURL = in.RedirectURL
+	}
+
 	var opts []oauth2.AuthCodeOption
-	if in.ApprovalForce {
-		opts = append(opts, oauth2.ApprovalForce)
+	prompt, err := promptParam(in.Prompt, in.ApprovalForce)
+	if err != nil {
+		return Response{}, err
+	}
+	if prompt != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("prompt", prompt))
+	}
+	if in.IncludeGrantedScopes {
+		opts = append(opts, oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+	}
+	if in.LoginHint != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("login_hint", in.LoginHint))
+	}
+	if in.HostedDomain != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("hd", in.HostedDomain))
 	}
 	if in.AccessType == "online" {
 		opts = append(opts, oauth2.AccessTypeOnline)
 	} else {
 		opts = append(opts, oauth2.AccessTypeOffline)
 	}
-	return config.AuthCodeURL("state-token", opts...), nil
+
+	var resp Response
+	if in.PKCE {
+		resp.CodeVerifier = oauth2.GenerateVerifier()
+		opts = append(opts, oauth2.S256ChallengeOption(resp.CodeVerifier))
+	}
+
+	key, err := etc.StateKey(in.Config, a.settings.StateSecret)
+	if err != nil {
+		return Response{}, err
+	}
+	state := etc.State{Value: in.State, Session: in.Session}
+	if a.settings.EmbedVerifier {
+		state.Verifier = resp.CodeVerifier
+	}
+	if resp.State, err = etc.SignState(key, state, time.Duration(a.settings.StateTTL)*time.Second); err != nil {
+		return Response{}, err
+	}
+
+	resp.AuthUrl = config.AuthCodeURL(resp.State, opts...)
+	return resp, nil
+}
+
+// promptParam validates prompt values, none can't be combined with others
+func promptParam(prompt string, approvalForce bool) (string, error) {
+	values := strings.Fields(prompt)
+	if approvalForce && !slices.Contains(values, "consent") {
+		values = append(values, "consent")
+	}
+	for _, v := range values {
+		switch v {
+		case "consent", "select_account":
+		case "none":
+			if len(values) > 1 {
+				return "", fmt.Errorf("prompt none can't be combined with other prompts")
+			}
+		default:
+			return "", fmt.Errorf("unknown prompt %q", v)
+		}
+	}
+	return strings.Join(values, " "), nil
 }
 
 func (a *Component) Ports() []module.Port {
@@ -122,6 +198,7 @@ func (a *Component) Ports() []module.Port {
 			Configuration: Request{
 				AccessType:    "offline",
 				ApprovalForce: true,
+				PKCE:          true,
 			},
 		},
 		{
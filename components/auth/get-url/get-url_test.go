@@ -0,0 +1,103 @@
+package get_url
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+)
+
+func TestGetAuthUrlState(t *testing.T) {
+	config := etc.ClientConfig{Credentials: `{"web":{"client_id":"id","client_secret":"secret","auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"https://oauth2.googleapis.com/token","redirect_uris":["https://example.com/callback"]}}`}
+	key, err := etc.StateKey(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		in       Request
+		session  string
+		want     etc.State
+	}{
+		{
+			name: "without session",
+			in:   Request{Config: config, State: "/return"},
+			want: etc.State{Value: "/return"},
+		},
+		{
+			name:    "with session",
+			in:      Request{Config: config, State: "/return", Session: "browser"},
+			session: "browser",
+			want:    etc.State{Value: "/return", Session: "browser"},
+		},
+		{
+			name:     "embedded verifier",
+			settings: Settings{EmbedVerifier: true},
+			in:       Request{Config: config, PKCE: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Component{settings: tt.settings}
+			resp, err := a.getAuthUrl(context.Background(), tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(resp.AuthUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Query().Get("state") != resp.State {
+				t.Fatalf("URL state %q differs from emitted state %q", u.Query().Get("state"), resp.State)
+			}
+
+			got, err := etc.VerifyState(key, resp.State, tt.session)
+			if err != nil {
+				t.Fatalf("state doesn't verify: %v", err)
+			}
+			want := tt.want
+			if tt.settings.EmbedVerifier {
+				if resp.CodeVerifier == "" || u.Query().Get("code_challenge") == "" {
+					t.Fatal("PKCE challenge is missing")
+				}
+				want.Verifier = resp.CodeVerifier
+			}
+			if got != want {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestPromptParam(t *testing.T) {
+	tests := []struct {
+		prompt        string
+		approvalForce bool
+		want          string
+		err           bool
+	}{
+		{prompt: "", want: ""},
+		{prompt: "", approvalForce: true, want: "consent"},
+		{prompt: "select_account", approvalForce: true, want: "select_account consent"},
+		{prompt: "consent", approvalForce: true, want: "consent"},
+		{prompt: "none", want: "none"},
+		{prompt: "none", approvalForce: true, err: true},
+		{prompt: "login", err: true},
+	}
+	for _, tt := range tests {
+		got, err := promptParam(tt.prompt, tt.approvalForce)
+		if tt.err {
+			if err == nil {
+				t.Errorf("promptParam(%q, %v) expected an error", tt.prompt, tt.approvalForce)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("promptParam(%q, %v) = %q, %v, want %q", tt.prompt, tt.approvalForce, got, err, tt.want)
+		}
+	}
+}
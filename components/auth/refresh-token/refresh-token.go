@@ -0,0 +1,212 @@
+package refresh_token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/module/api/v1alpha1"
+	"github.com/tiny-systems/module/module"
+	"github.com/tiny-systems/module/registry"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	ComponentName   = "oauth_token_refresh"
+	RequestPort     = "request"
+	ResponsePort    = "response"
+	ReauthorizePort = "reauthorize"
+	ErrorPort       = "error"
+)
+
+// invalidGrant is returned by the token endpoint when the refresh token was revoked or expired
+const invalidGrant = "invalid_grant"
+
+type Context any
+
+type Request struct {
+	Context Context          `json:"context,omitempty" title:"Context" configurable:"true"`
+	Config  etc.ClientConfig `json:"config" title:"Config" required:"true" description:"Client Config"`
+	Token   etc.Token        `json:"token" title:"Token" required:"true"`
+	Force   bool             `json:"force" title:"Force" description:"Refresh even if the access token is still valid"`
+}
+
+type Settings struct {
+	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+}
+
+type Response struct {
+	Context   Context   `json:"context" title:"Context"`
+	Token     etc.Token `json:"token"`
+	Refreshed bool      `json:"refreshed" title:"Refreshed" description:"False if the token was still valid and is emitted as is"`
+}
+
+// Reauthorization is emitted when the user has to go through the consent screen again
+type Reauthorization struct {
+	Context Context `json:"context" title:"Context"`
+	Error   string  `json:"error"`
+}
+
+type Error struct {
+	Context Context `json:"context"`
+	Error   string  `json:"error"`
+}
+
+// errReauthorize marks failures only a new authorization can fix
+var errReauthorize = errors.New("reauthorization required")
+
+type Component struct {
+	settings Settings
+}
+
+func (a *Component) GetInfo() module.ComponentInfo {
+	return module.ComponentInfo{
+		Name:        ComponentName,
+		Description: "Refresh Token",
+		Info:        "Refreshes an expired OAuth token with its refresh token and emits the renewed token to store. Revoked or expired refresh tokens go to the Reauthorize port",
+		Tags:        []string{"google", "auth"},
+	}
+}
+
+func (a *Component) refresh(ctx context.Context, in Request) (*oauth2.Token, bool, error) {
+	current := &oauth2.Token{
+		AccessToken:  in.Token.AccessToken,
+		TokenType:    in.Token.TokenType,
+		RefreshToken: in.Token.RefreshToken,
+		Expiry:       in.Token.Expiry,
+	}
+	if !in.Force && current.Valid() {
+		return current, false, nil
+	}
+	if current.RefreshToken == "" {
+		return nil, false, fmt.Errorf("%w: token has no refresh token", errReauthorize)
+	}
+
+	config, err := google.ConfigFromJSON([]byte(in.Config.Credentials), in.Config.Scopes...)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to parse client secret file to config: %v", err)
+	}
+
+	// without an access token the token source always goes to the token endpoint
+	token, err := config.TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+	if err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) && re.ErrorCode == invalidGrant {
+			return nil, false, fmt.Errorf("%w: %v", errReauthorize, err)
+		}
+		return nil, false, err
+	}
+	// Google omits the refresh token unless it was rotated
+	if token.RefreshToken == "" {
+		token.RefreshToken = current.RefreshToken
+	}
+	return token, true, nil
+}
+
+// OnSettings stores the component settings.
+func (a *Component) OnSettings(_ context.Context, msg any) error {
+	in, ok := msg.(Settings)
+	if !ok {
+		return fmt.Errorf("invalid settings")
+	}
+	a.settings = in
+	return nil
+}
+
+// Handle dispatches business ports. System ports go through capabilities.
+func (a *Component) Handle(ctx context.Context, output module.Handler, port string, msg any) module.Result {
+	if port != RequestPort {
+		return module.Fail(fmt.Errorf("unknown port %s", port))
+	}
+
+	in, ok := msg.(Request)
+	if !ok {
+		return module.Fail(fmt.Errorf("invalid input message"))
+	}
+
+	token, refreshed, err := a.refresh(ctx, in)
+	if errors.Is(err, errReauthorize) {
+		return output(ctx, ReauthorizePort, Reauthorization{
+			Context: in.Context,
+			Error:   err.Error(),
+		})
+	}
+	if err != nil {
+		// check err port
+		if !a.settings.EnableErrorPort {
+			return module.Fail(err)
+		}
+		return output(ctx, ErrorPort, Error{
+			Context: in.Context,
+			Error:   err.Error(),
+		})
+	}
+
+	return output(ctx, ResponsePort, Response{
+		Context: in.Context,
+		Token: etc.Token{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			TokenType:    token.TokenType,
+			Expiry:       token.Expiry,
+		},
+		Refreshed: refreshed,
+	})
+}
+
+func (a *Component) Ports() []module.Port {
+	ports := []module.Port{
+		{
+			Name:          v1alpha1.SettingsPort,
+			Label:         "Settings",
+			Configuration: Settings{},
+		},
+		{
+			Name:          RequestPort,
+			Label:         "Request",
+			Position:      module.Left,
+			Configuration: Request{},
+		},
+		{
+			Source:        true,
+			Name:          ResponsePort,
+			Label:         "Response",
+			Position:      module.Right,
+			Configuration: Response{},
+		},
+		{
+			Source:        true,
+			Name:          ReauthorizePort,
+			Label:         "Reauthorize",
+			Position:      module.Right,
+			Configuration: Reauthorization{},
+		},
+	}
+
+	if !a.settings.EnableErrorPort {
+		return ports
+	}
+
+	return append(ports, module.Port{
+		Position:      module.Bottom,
+		Name:          ErrorPort,
+		Label:         "Error",
+		Source:        true,
+		Configuration: Error{},
+	})
+}
+
+func (a *Component) Instance() module.Component {
+	return &Component{}
+}
+
+var (
+	_ module.Component       = (*Component)(nil)
+	_ module.SettingsHandler = (*Component)(nil)
+)
+
+func init() {
+	registry.Register(&Component{})
+}
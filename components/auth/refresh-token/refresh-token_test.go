@@ -0,0 +1,124 @@
+package refresh_token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/module/module"
+)
+
+func TestHandle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.FormValue("refresh_token") {
+		case "valid":
+			_, _ = w.Write([]byte(`{"access_token":"renewed","token_type":"Bearer","expires_in":3600}`))
+		case "revoked":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"internal_failure"}`))
+		}
+	}))
+	defer server.Close()
+
+	config := etc.ClientConfig{Credentials: `{"web":{"client_id":"id","client_secret":"secret","auth_uri":"` + server.URL + `/auth","token_uri":"` + server.URL + `/token","redirect_uris":["http://localhost/callback"]}}`}
+	expired := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		settings  Settings
+		in        Request
+		port      string
+		token     string
+		refreshed bool
+		fail      bool
+	}{
+		{
+			name:  "valid token",
+			in:    Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "valid", Expiry: time.Now().Add(time.Hour)}},
+			port:  ResponsePort,
+			token: "current",
+		},
+		{
+			name:      "forced",
+			in:        Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "valid", Expiry: time.Now().Add(time.Hour)}, Force: true},
+			port:      ResponsePort,
+			token:     "renewed",
+			refreshed: true,
+		},
+		{
+			name:      "expired",
+			in:        Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "valid", Expiry: expired}},
+			port:      ResponsePort,
+			token:     "renewed",
+			refreshed: true,
+		},
+		{
+			name: "revoked",
+			in:   Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "revoked", Expiry: expired}},
+			port: ReauthorizePort,
+		},
+		{
+			name: "no refresh token",
+			in:   Request{Config: config, Token: etc.Token{AccessToken: "current", Expiry: expired}},
+			port: ReauthorizePort,
+		},
+		{
+			name:     "server error",
+			settings: Settings{EnableErrorPort: true},
+			in:       Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "broken", Expiry: expired}},
+			port:     ErrorPort,
+		},
+		{
+			name: "server error without error port",
+			in:   Request{Config: config, Token: etc.Token{AccessToken: "current", RefreshToken: "broken", Expiry: expired}},
+			fail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				port string
+				data any
+			)
+			handler := func(_ context.Context, p string, d any) module.Result {
+				port, data = p, d
+				return module.Ok(nil)
+			}
+
+			c := &Component{settings: tt.settings}
+			r := c.Handle(context.Background(), handler, RequestPort, tt.in)
+			if tt.fail {
+				if !r.IsErr() {
+					t.Fatalf("expected a failure, got %s", port)
+				}
+				return
+			}
+			if r.IsErr() {
+				t.Fatal(r.Err())
+			}
+			if port != tt.port {
+				t.Fatalf("emitted to %s, want %s: %+v", port, tt.port, data)
+			}
+			if tt.port != ResponsePort {
+				return
+			}
+
+			resp := data.(Response)
+			if resp.Token.AccessToken != tt.token || resp.Refreshed != tt.refreshed {
+				t.Fatalf("unexpected response %+v", resp)
+			}
+			// Google omits unrotated refresh tokens
+			if resp.Token.RefreshToken != tt.in.Token.RefreshToken {
+				t.Fatalf("refresh token %q, want %q", resp.Token.RefreshToken, tt.in.Token.RefreshToken)
+			}
+		})
+	}
+}
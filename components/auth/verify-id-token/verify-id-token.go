@@ -0,0 +1,174 @@
+package verify_id_token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tiny-systems/googleapis-module/pkg/idtoken"
+	"github.com/tiny-systems/module/api/v1alpha1"
+	"github.com/tiny-systems/module/module"
+	"github.com/tiny-systems/module/registry"
+)
+
+const (
+	ComponentName = "google_id_token_verify"
+	RequestPort   = "request"
+	ResponsePort  = "response"
+	ErrorPort     = "error"
+)
+
+type Context any
+
+type Request struct {
+	Context              Context  `json:"context,omitempty" title:"Context" configurable:"true"`
+	IDToken              string   `json:"idToken" required:"true" title:"ID Token" description:"JWT to verify, without the Bearer prefix"`
+	Audience             []string `json:"audience" required:"true" title:"Audience" description:"Accepted aud values: the OAuth client ID for sign-in, the push endpoint URL for Pub/Sub and Cloud Tasks, /projects/NUMBER/... for IAP"`
+	HostedDomain         string   `json:"hostedDomain,omitempty" title:"Hosted Domain" description:"Google Workspace domain the user must belong to (hd claim)"`
+	RequireVerifiedEmail bool     `json:"requireVerifiedEmail" title:"Require Verified Email" description:"Reject tokens whose email is not verified"`
+}
+
+type Settings struct {
+	EnableErrorPort bool     `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	JWKSURL         string   `json:"jwksUrl,omitempty" title:"JWKS URL" description:"Signing keys of the issuer. Google's keys if empty, use https://www.gstatic.com/iap/verify/public_key-jwk for IAP or a local server for testing"`
+	Issuers         []string `json:"issuers,omitempty" title:"Issuers" description:"Accepted iss values. Google accounts if empty, https://cloud.google.com/iap for IAP"`
+}
+
+type Response struct {
+	Context       Context        `json:"context" title:"Context"`
+	Subject       string         `json:"subject" title:"Subject" description:"Unique ID of the account"`
+	Email         string         `json:"email,omitempty" title:"Email"`
+	EmailVerified bool           `json:"emailVerified" title:"Email Verified"`
+	HostedDomain  string         `json:"hostedDomain,omitempty" title:"Hosted Domain"`
+	Issuer        string         `json:"issuer" title:"Issuer"`
+	Audience      []string       `json:"audience" title:"Audience"`
+	Expiry        time.Time      `json:"expiry" title:"Expiry"`
+	Claims        map[string]any `json:"claims" title:"Claims" description:"All claims of the token"`
+}
+
+type Error struct {
+	Context Context `json:"context"`
+	Error   string  `json:"error"`
+}
+
+type Component struct {
+	settings Settings
+}
+
+func (a *Component) GetInfo() module.ComponentInfo {
+	return module.ComponentInfo{
+		Name:        ComponentName,
+		Description: "Verify ID Token",
+		Info:        "Verifies Google-issued JWTs from sign-in, Pub/Sub push, Cloud Tasks or IAP against the issuer's signing keys and emits the claims",
+		Tags:        []string{"google", "auth"},
+	}
+}
+
+func (a *Component) verify(ctx context.Context, in Request) (Response, error) {
+	claims, err := idtoken.Verify(ctx, strings.TrimPrefix(in.IDToken, "Bearer "), idtoken.Options{
+		Audiences:            in.Audience,
+		Issuers:              a.settings.Issuers,
+		JWKSURL:              a.settings.JWKSURL,
+		HostedDomain:         in.HostedDomain,
+		RequireVerifiedEmail: in.RequireVerifiedEmail,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{
+		Context:       in.Context,
+		Subject:       claims.String("sub"),
+		Email:         claims.String("email"),
+		EmailVerified: claims.Bool("email_verified"),
+		HostedDomain:  claims.String("hd"),
+		Issuer:        claims.String("iss"),
+		Audience:      claims.Audience(),
+		Expiry:        claims.Time("exp"),
+		Claims:        claims,
+	}, nil
+}
+
+// OnSettings stores the component settings.
+func (a *Component) OnSettings(_ context.Context, msg any) error {
+	in, ok := msg.(Settings)
+	if !ok {
+		return fmt.Errorf("invalid settings")
+	}
+	a.settings = in
+	return nil
+}
+
+// Handle dispatches business ports. System ports go through capabilities.
+func (a *Component) Handle(ctx context.Context, output module.Handler, port string, msg any) module.Result {
+	if port != RequestPort {
+		return module.Fail(fmt.Errorf("unknown port %s", port))
+	}
+
+	in, ok := msg.(Request)
+	if !ok {
+		return module.Fail(fmt.Errorf("invalid input message"))
+	}
+
+	resp, err := a.verify(ctx, in)
+	if err != nil {
+		// check err port
+		if !a.settings.EnableErrorPort {
+			return module.Fail(err)
+		}
+		return output(ctx, ErrorPort, Error{
+			Context: in.Context,
+			Error:   err.Error(),
+		})
+	}
+
+	return output(ctx, ResponsePort, resp)
+}
+
+func (a *Component) Ports() []module.Port {
+	ports := []module.Port{
+		{
+			Name:          v1alpha1.SettingsPort,
+			Label:         "Settings",
+			Configuration: Settings{},
+		},
+		{
+			Name:          RequestPort,
+			Label:         "Request",
+			Position:      module.Left,
+			Configuration: Request{},
+		},
+		{
+			Source:        true,
+			Name:          ResponsePort,
+			Label:         "Response",
+			Position:      module.Right,
+			Configuration: Response{},
+		},
+	}
+
+	if !a.settings.EnableErrorPort {
+		return ports
+	}
+
+	return append(ports, module.Port{
+		Position:      module.Bottom,
+		Name:          ErrorPort,
+		Label:         "Error",
+		Source:        true,
+		Configuration: Error{},
+	})
+}
+
+func (a *Component) Instance() module.Component {
+	return &Component{}
+}
+
+var (
+	_ module.Component       = (*Component)(nil)
+	_ module.SettingsHandler = (*Component)(nil)
+)
+
+func init() {
+	registry.Register(&Component{})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -16,6 +18,8 @@ const (
 	RequestPort   = "request"
 	ResponsePort  = "response"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 type Channel struct {
@@ -25,7 +29,9 @@ type Channel struct {
 }
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort          bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" description:"Emit OAuth tokens renewed during the call, so flows can store them"`
 }
 
 type Context any
@@ -38,7 +44,14 @@ type Request struct {
 }
 
 type Response struct {
-	Context Context `json:"context"`
+	Context Context    `json:"context"`
+	Token   *etc.Token `json:"token,omitempty" title:"Token" description:"Current OAuth token, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context Context   `json:"context"`
+	Token   etc.Token `json:"token"`
 }
 
 type Error struct {
@@ -86,7 +99,7 @@ func (h *Component) Handle(ctx context.Context, handler module.Handler, port str
 		return module.Fail(fmt.Errorf("invalid message"))
 	}
 
-	err := h.stop(ctx, req)
+	token, refreshed, err := h.stop(ctx, req)
 	if err != nil {
 		if !h.settings.EnableErrorPort {
 			return module.Fail(err)
@@ -96,27 +109,44 @@ func (h *Component) Handle(ctx context.Context, handler module.Handler, port str
 			Error:   err.Error(),
 		})
 	}
+	if refreshed && h.settings.EnableTokenRefreshedPort {
+		if r := handler(ctx, TokenRefreshedPort, TokenRefreshed{Context: req.Context, Token: *token}); r.IsErr() {
+			return r
+		}
+	}
 	return handler(ctx, ResponsePort, Response{
 		Context: req.Context,
+		Token:   token,
 	})
 
 }
 
-func (h *Component) stop(ctx context.Context, req Request) error {
-	client, err := etc.NewGoogleHTTPClient(ctx, req.Config, req.Token)
+// stop stops the channel and returns the current token and whether it was refreshed
+func (h *Component) stop(ctx context.Context, req Request) (*etc.Token, bool, error) {
+	if err := etc.RateLimit(ctx, etc.CalendarService, req.Config, req.Token, h.settings.RateLimit); err != nil {
+		return nil, false, err
+	}
+
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
 	if err != nil {
-		return fmt.Errorf("unable to create google client: %v", err)
+		return nil, false, fmt.Errorf("unable to create google client: %v", err)
 	}
+	defer release()
 
 	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return fmt.Errorf("unable to retrieve calendar client: %v", err)
+		return nil, false, fmt.Errorf("unable to retrieve calendar client: %v", err)
 	}
-	return srv.Channels.Stop(&calendar.Channel{
+	err = srv.Channels.Stop(&calendar.Channel{
 		Token:      req.Channel.Token,
 		Id:         req.Channel.ID,
 		ResourceId: req.Channel.ResourceId,
-	}).Do()
+	}).Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.channels.stop")).Do()
+	if err != nil {
+		return nil, false, err
+	}
+	token, refreshed := etc.CurrentToken(client, req.Token)
+	return token, refreshed, nil
 }
 
 func (h *Component) Ports() []module.Port {
@@ -145,6 +175,15 @@ func (h *Component) Ports() []module.Port {
 			Configuration: Response{},
 		},
 	}
+	if h.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Source:        true,
+			Position:      module.Right,
+			Configuration: TokenRefreshed{},
+		})
+	}
 	if !h.settings.EnableErrorPort {
 		return ports
 	}
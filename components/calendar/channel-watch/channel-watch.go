@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -16,6 +18,8 @@ const (
 	RequestPort   = "request"
 	ResponsePort  = "response"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 type Channel struct {
@@ -29,7 +33,9 @@ type Channel struct {
 }
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort          bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" description:"Emit OAuth tokens renewed during the call, so flows can store them"`
 }
 
 type Context any
@@ -58,6 +64,13 @@ type WatchChannel struct {
 type Response struct {
 	Context Context      `json:"context"`
 	Channel WatchChannel `json:"channel"`
+	Token   *etc.Token   `json:"token,omitempty" title:"Token" description:"Current OAuth token, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context Context   `json:"context"`
+	Token   etc.Token `json:"token"`
 }
 
 type Error struct {
@@ -105,7 +118,7 @@ func (h *Component) Handle(ctx context.Context, handler module.Handler, port str
 		return module.Fail(fmt.Errorf("invalid message"))
 	}
 
-	ch, err := h.watch(ctx, req)
+	ch, token, refreshed, err := h.watch(ctx, req)
 	if err != nil {
 		if !h.settings.EnableErrorPort {
 			return module.Fail(err)
@@ -116,8 +129,14 @@ func (h *Component) Handle(ctx context.Context, handler module.Handler, port str
 		})
 	}
 
+	if refreshed && h.settings.EnableTokenRefreshedPort {
+		if r := handler(ctx, TokenRefreshedPort, TokenRefreshed{Context: req.Context, Token: *token}); r.IsErr() {
+			return r
+		}
+	}
 	return handler(ctx, ResponsePort, Response{
 		Context: req.Context,
+		Token:   token,
 		Channel: WatchChannel{
 			ID:          ch.Id,
 			Kind:        ch.Kind,
@@ -130,24 +149,35 @@ func (h *Component) Handle(ctx context.Context, handler module.Handler, port str
 
 }
 
-func (h *Component) watch(ctx context.Context, req Request) (*calendar.Channel, error) {
-	client, err := etc.NewGoogleHTTPClient(ctx, req.Config, req.Token)
+// watch starts the channel and returns the current token and whether it was refreshed
+func (h *Component) watch(ctx context.Context, req Request) (*calendar.Channel, *etc.Token, bool, error) {
+	if err := etc.RateLimit(ctx, etc.CalendarService, req.Config, req.Token, h.settings.RateLimit); err != nil {
+		return nil, nil, false, err
+	}
+
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create google client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to create google client: %v", err)
 	}
+	defer release()
 
 	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve calendar client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to retrieve calendar client: %v", err)
 	}
 
-	return srv.Events.Watch(req.Calendar.ID, &calendar.Channel{
+	ch, err := srv.Events.Watch(req.Calendar.ID, &calendar.Channel{
 		Type:       req.Channel.Type,
 		Address:    req.Channel.Address,
 		Token:      req.Channel.Token,
 		Id:         req.Channel.ID,
 		Expiration: req.Channel.Expiration,
-	}).Do()
+	}).Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.events.watch")).Do()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	token, refreshed := etc.CurrentToken(client, req.Token)
+	return ch, token, refreshed, nil
 }
 
 func (h *Component) Ports() []module.Port {
@@ -178,6 +208,15 @@ func (h *Component) Ports() []module.Port {
 			Configuration: Response{},
 		},
 	}
+	if h.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Source:        true,
+			Position:      module.Right,
+			Configuration: TokenRefreshed{},
+		})
+	}
 	if !h.settings.EnableErrorPort {
 		return ports
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -16,12 +18,16 @@ const (
 	RequestPort   = "request"
 	ResponsePort  = "response"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 type Context any
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort          bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" description:"Emit OAuth tokens renewed during the call, so flows can store them"`
 }
 
 type Component struct {
@@ -37,6 +43,13 @@ type Request struct {
 type Response struct {
 	Context   Context                       `json:"context" title:"Context" configurable:"true"`
 	Calendars []*calendar.CalendarListEntry `json:"calendars"`
+	Token     *etc.Token                    `json:"token,omitempty" title:"Token" description:"Current OAuth token, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context Context   `json:"context"`
+	Token   etc.Token `json:"token"`
 }
 
 type Error struct {
@@ -74,7 +87,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		return module.Fail(fmt.Errorf("invalid input message"))
 	}
 
-	calendars, err := g.getCalendars(ctx, in)
+	calendars, token, refreshed, err := g.getCalendars(ctx, in)
 	if err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
@@ -86,30 +99,43 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		})
 	}
 
+	if refreshed && g.settings.EnableTokenRefreshedPort {
+		if r := output(ctx, TokenRefreshedPort, TokenRefreshed{Context: in.Context, Token: *token}); r.IsErr() {
+			return r
+		}
+	}
 	return output(ctx, ResponsePort, Response{
 		Context:   in.Context,
 		Calendars: calendars,
+		Token:     token,
 	})
 
 }
 
-func (c *Component) getCalendars(ctx context.Context, req Request) ([]*calendar.CalendarListEntry, error) {
+// getCalendars lists calendars and returns the current token and whether it was refreshed
+func (c *Component) getCalendars(ctx context.Context, req Request) ([]*calendar.CalendarListEntry, *etc.Token, bool, error) {
 
-	client, err := etc.NewGoogleHTTPClient(ctx, req.Config, req.Token)
+	if err := etc.RateLimit(ctx, etc.CalendarService, req.Config, req.Token, c.settings.RateLimit); err != nil {
+		return nil, nil, false, err
+	}
+
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create google client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to create google client: %v", err)
 	}
+	defer release()
 
 	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve calendar client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to retrieve calendar client: %v", err)
 	}
 
-	list, err := srv.CalendarList.List().Context(ctx).Do()
+	list, err := srv.CalendarList.List().Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.calendarList.list")).Do()
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
 	}
-	return list.Items, nil
+	token, refreshed := etc.CurrentToken(client, req.Token)
+	return list.Items, token, refreshed, nil
 }
 
 func (g *Component) Ports() []module.Port {
@@ -133,6 +159,15 @@ func (g *Component) Ports() []module.Port {
 			Configuration: Response{},
 		},
 	}
+	if g.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Source:        true,
+			Position:      module.Right,
+			Configuration: TokenRefreshed{},
+		})
+	}
 	if !g.settings.EnableErrorPort {
 		return ports
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -17,6 +19,8 @@ const (
 	RequestPort   = "request"
 	ResponsePort  = "response"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 type Context any
@@ -43,6 +47,13 @@ type Error struct {
 type Response struct {
 	Context Context         `json:"context"`
 	Results calendar.Events `json:"results"`
+	Token   *etc.Token      `json:"token,omitempty" title:"Token" description:"Current OAuth token, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context Context   `json:"context"`
+	Token   etc.Token `json:"token"`
 }
 
 type Component struct {
@@ -50,14 +61,16 @@ type Component struct {
 }
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" default:"false" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort          bool               `json:"enableErrorPort" default:"false" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" description:"Emit OAuth tokens renewed during the call, so flows can store them"`
 }
 
 func (c *Component) GetInfo() module.ComponentInfo {
 	return module.ComponentInfo{
 		Name:        ComponentName,
 		Description: "Calendar Get Events",
-		Info:        "Calendar Get Events",
+		Info:        "Fetches events of a calendar. Public calendars can be read with an API key in the client config instead of credentials.",
 		Tags:        []string{"Google", "Calendar"},
 	}
 }
@@ -82,7 +95,7 @@ func (c *Component) Handle(ctx context.Context, handler module.Handler, port str
 	if !ok {
 		return module.Fail(fmt.Errorf("invalid message"))
 	}
-	events, err := c.getEvents(ctx, req)
+	events, token, refreshed, err := c.getEvents(ctx, req)
 	if err != nil {
 		if !c.settings.EnableErrorPort {
 			return module.Fail(err)
@@ -93,23 +106,35 @@ func (c *Component) Handle(ctx context.Context, handler module.Handler, port str
 		})
 	}
 
+	if refreshed && c.settings.EnableTokenRefreshedPort {
+		if r := handler(ctx, TokenRefreshedPort, TokenRefreshed{Context: req.Context, Token: *token}); r.IsErr() {
+			return r
+		}
+	}
 	return handler(ctx, ResponsePort, Response{
 		Context: req.Context,
 		Results: *events,
+		Token:   token,
 	})
 
 }
 
-func (c *Component) getEvents(ctx context.Context, req Request) (*calendar.Events, error) {
+// getEvents lists events and returns the current token and whether it was refreshed
+func (c *Component) getEvents(ctx context.Context, req Request) (*calendar.Events, *etc.Token, bool, error) {
 
-	client, err := etc.NewGoogleHTTPClient(ctx, req.Config, req.Token)
+	if err := etc.RateLimit(ctx, etc.CalendarService, req.Config, req.Token, c.settings.RateLimit); err != nil {
+		return nil, nil, false, err
+	}
+
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create google client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to create google client: %v", err)
 	}
+	defer release()
 
 	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve calendar client: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to retrieve calendar client: %v", err)
 	}
 
 	call := srv.Events.List(req.CalendarId).ShowDeleted(req.ShowDeleted).SingleEvents(req.SingleEvents)
@@ -136,12 +161,13 @@ func (c *Component) getEvents(ctx context.Context, req Request) (*calendar.Event
 	}
 	call.MaxResults(maxResults)
 
-	events, err := call.Do()
+	events, err := call.Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.events.list")).Do()
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve user's events: %v", err)
+		return nil, nil, false, fmt.Errorf("unable to retrieve user's events: %v", err)
 	}
 
-	return events, nil
+	token, refreshed := etc.CurrentToken(client, req.Token)
+	return events, token, refreshed, nil
 }
 
 func (c *Component) Ports() []module.Port {
@@ -173,6 +199,15 @@ func (c *Component) Ports() []module.Port {
 			Configuration: Response{},
 		}}
 
+	if c.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Source:        true,
+			Position:      module.Right,
+			Configuration: TokenRefreshed{},
+		})
+	}
 	if !c.settings.EnableErrorPort {
 		return ports
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -16,12 +18,16 @@ const (
 	RequestPort   = "request"
 	ResponsePort  = "response"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 type Context any
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort          bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" description:"Emit OAuth tokens renewed during the call, so flows can store them"`
 }
 
 type Component struct {
@@ -39,7 +45,14 @@ type Request struct {
 }
 
 type Response struct {
-	Context Context `json:"context"`
+	Context Context    `json:"context"`
+	Token   *etc.Token `json:"token,omitempty" title:"Token" description:"Current OAuth token, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context Context   `json:"context"`
+	Token   etc.Token `json:"token"`
 }
 
 type Error struct {
@@ -77,7 +90,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		return module.Fail(fmt.Errorf("invalid input message"))
 	}
 
-	err := g.responseEvent(ctx, in)
+	token, refreshed, err := g.responseEvent(ctx, in)
 	if err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
@@ -89,27 +102,39 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		})
 	}
 
+	if refreshed && g.settings.EnableTokenRefreshedPort {
+		if r := output(ctx, TokenRefreshedPort, TokenRefreshed{Context: in.Context, Token: *token}); r.IsErr() {
+			return r
+		}
+	}
 	return output(ctx, ResponsePort, Response{
 		Context: in.Context,
+		Token:   token,
 	})
 
 }
 
-func (c *Component) responseEvent(ctx context.Context, req Request) error {
+// responseEvent updates the attendee response and returns the current token and whether it was refreshed
+func (c *Component) responseEvent(ctx context.Context, req Request) (*etc.Token, bool, error) {
+
+	if err := etc.RateLimit(ctx, etc.CalendarService, req.Config, req.Token, c.settings.RateLimit); err != nil {
+		return nil, false, err
+	}
 
-	client, err := etc.NewGoogleHTTPClient(ctx, req.Config, req.Token)
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
 	if err != nil {
-		return fmt.Errorf("unable to create google client: %v", err)
+		return nil, false, fmt.Errorf("unable to create google client: %v", err)
 	}
+	defer release()
 
 	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return fmt.Errorf("unable to retrieve calendar client: %v", err)
+		return nil, false, fmt.Errorf("unable to retrieve calendar client: %v", err)
 	}
 
-	event, err := srv.Events.Get(req.CalendarID, req.EventID).Context(ctx).Do()
+	event, err := srv.Events.Get(req.CalendarID, req.EventID).Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.events.get")).Do()
 	if err != nil {
-		return fmt.Errorf("unable to retrieve event: %v", err)
+		return nil, false, fmt.Errorf("unable to retrieve event: %v", err)
 	}
 	//
 
@@ -120,9 +145,12 @@ func (c *Component) responseEvent(ctx context.Context, req Request) error {
 		a.ResponseStatus = req.ResponseStatus
 	}
 
-	_, err = srv.Events.Update(req.CalendarID, req.EventID, event).Context(ctx).Do()
-
-	return err
+	_, err = srv.Events.Update(req.CalendarID, req.EventID, event).Context(telemetry.WithCall(ctx, etc.CalendarService, "calendar.events.update")).Do()
+	if err != nil {
+		return nil, false, err
+	}
+	token, refreshed := etc.CurrentToken(client, req.Token)
+	return token, refreshed, nil
 }
 
 func (g *Component) Ports() []module.Port {
@@ -146,6 +174,15 @@ func (g *Component) Ports() []module.Port {
 			Configuration: Response{},
 		},
 	}
+	if g.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Source:        true,
+			Position:      module.Right,
+			Configuration: TokenRefreshed{},
+		})
+	}
 	if !g.settings.EnableErrorPort {
 		return ports
 	}
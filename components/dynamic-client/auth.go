@@ -0,0 +1,100 @@
+package dynamicclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
+	"golang.org/x/oauth2"
+)
+
+// Auth modes
+const (
+	AuthToken       = "token"
+	AuthCredentials = "credentials"
+	AuthAPIKey      = "apiKey"
+	AuthNone        = "none"
+)
+
+const requestTimeout = 30 * time.Second
+
+// AuthSettings selects how requests are authorized
+type AuthSettings struct {
+	Mode          string `json:"mode" title:"Mode" default:"token" enum:"token,credentials,apiKey,none" enumTitles:"OAuth token,Client credentials,API key,None" description:"OAuth token sends the request token, client credentials (including application default credentials) and API key use the request config, none sends requests as is"`
+	APIKeyInQuery bool   `json:"apiKeyInQuery" title:"API Key In Query" description:"Send the API key as key query parameter instead of the X-Goog-Api-Key header"`
+}
+
+// newHTTPClient returns a client authorizing requests according to the auth mode and a func releasing it
+func newHTTPClient(ctx context.Context, settings AuthSettings, method googleapismodule.Method, req Request) (*http.Client, func(), error) {
+	// Requests are traced and measured, and recorded or replayed in cassette mode
+	base := cassette.NewTransport(telemetry.NewTransport(http.DefaultTransport))
+
+	var transport http.RoundTripper
+	switch settings.Mode {
+	case AuthToken, "":
+		if req.Token.AccessToken == "" {
+			return nil, nil, fmt.Errorf("access token is required")
+		}
+		transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: req.Token.AccessToken, TokenType: req.Token.TokenType}),
+			Base:   base,
+		}
+	case AuthCredentials:
+		config := req.Config
+		if config.Credentials == "" && !config.ADC {
+			return nil, nil, fmt.Errorf("client credentials or application default credentials are required")
+		}
+		if len(config.Scopes) == 0 {
+			config.Scopes = method.Scopes
+		}
+		shared, release, err := etc.SharedGoogleHTTPClient(ctx, config, token(req))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to create google client: %w", err)
+		}
+		// the shared client is not modified, its transport keeps the cached token
+		client := *shared
+		client.Timeout = requestTimeout
+		return &client, release, nil
+	case AuthAPIKey:
+		if req.Config.APIKey == "" {
+			return nil, nil, fmt.Errorf("API key is required")
+		}
+		transport = &etc.APIKeyTransport{Key: req.Config.APIKey, InQuery: settings.APIKeyInQuery, Base: base}
+	case AuthNone:
+		transport = base
+	default:
+		return nil, nil, fmt.Errorf("unknown auth mode %s", settings.Mode)
+	}
+	return &http.Client{Timeout: requestTimeout, Transport: transport}, func() {}, nil
+}
+
+// token returns the request token for credentials mode, OAuth2 client credentials require one
+func token(req Request) *etc.Token {
+	if req.Token.AccessToken == "" && req.Token.RefreshToken == "" {
+		return nil
+	}
+	return &etc.Token{
+		AccessToken:  req.Token.AccessToken,
+		TokenType:    req.Token.TokenType,
+		RefreshToken: req.Token.RefreshToken,
+		Expiry:       req.Token.Expiry,
+	}
+}
+
+// principal identifies the caller for per-user caching and rate limiting
+func principal(settings AuthSettings, req Request) string {
+	switch settings.Mode {
+	case AuthCredentials:
+		return etc.Principal(req.Config, token(req))
+	case AuthAPIKey:
+		return etc.APIKeyPrincipal(req.Config.APIKey)
+	case AuthNone:
+		return ""
+	}
+	return etc.TokenPrincipal(req.Token.AccessToken, req.Token.RefreshToken)
+}
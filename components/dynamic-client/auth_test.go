@@ -0,0 +1,102 @@
+package dynamicclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/components/etc"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		settings AuthSettings
+		req      Request
+		header   string
+		value    string
+		query    string
+		err      bool
+	}{
+		{
+			name:     "token",
+			settings: AuthSettings{Mode: AuthToken},
+			req:      Request{Token: Token{AccessToken: "access", TokenType: "Bearer"}},
+			header:   "Authorization",
+			value:    "Bearer access",
+		},
+		{name: "token missing", settings: AuthSettings{Mode: AuthToken}, err: true},
+		{
+			name:     "api key in header",
+			settings: AuthSettings{Mode: AuthAPIKey},
+			req:      Request{Config: etc.ClientConfig{APIKey: "public-key"}},
+			header:   etc.APIKeyHeader,
+			value:    "public-key",
+		},
+		{
+			name:     "api key in query",
+			settings: AuthSettings{Mode: AuthAPIKey, APIKeyInQuery: true},
+			req:      Request{Config: etc.ClientConfig{APIKey: "public-key"}},
+			query:    "public-key",
+		},
+		{name: "api key missing", settings: AuthSettings{Mode: AuthAPIKey}, err: true},
+		{name: "credentials missing", settings: AuthSettings{Mode: AuthCredentials}, req: Request{Config: etc.ClientConfig{APIKey: "public-key"}}, err: true},
+		{name: "none", settings: AuthSettings{Mode: AuthNone}},
+		{name: "unknown mode", settings: AuthSettings{Mode: "basic"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, release, err := newHTTPClient(context.Background(), tt.settings, googleapismodule.Method{}, tt.req)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer release()
+
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if tt.header != "" && got.Header.Get(tt.header) != tt.value {
+				t.Errorf("header %s = %q, want %q", tt.header, got.Header.Get(tt.header), tt.value)
+			}
+			if q := got.URL.Query().Get(etc.APIKeyParam); q != tt.query {
+				t.Errorf("key query parameter %q, want %q", q, tt.query)
+			}
+		})
+	}
+}
+
+func TestPrincipalByAuthMode(t *testing.T) {
+	req := Request{
+		Token:  Token{AccessToken: "access"},
+		Config: etc.ClientConfig{APIKey: "public-key"},
+	}
+	if principal(AuthSettings{Mode: AuthNone}, req) != "" {
+		t.Error("requests without auth have a principal")
+	}
+	if principal(AuthSettings{Mode: AuthToken}, req) == principal(AuthSettings{Mode: AuthAPIKey}, req) {
+		t.Error("token and API key share a principal")
+	}
+	other := req
+	other.Config.APIKey = "other-key"
+	if principal(AuthSettings{Mode: AuthAPIKey}, req) == principal(AuthSettings{Mode: AuthAPIKey}, other) {
+		t.Error("API keys share a principal")
+	}
+}
@@ -0,0 +1,271 @@
+package dynamicclient
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	defaultCacheTTL        = 60
+	defaultCacheMaxEntries = 1000
+)
+
+// CacheSettings configures the response cache for read-only methods
+type CacheSettings struct {
+	Enabled            bool `json:"enabled" title:"Enable Cache" description:"Cache responses of read-only (GET) methods"`
+	TTL                int  `json:"ttl" title:"TTL" default:"60" minimum:"1" description:"Seconds a cached response is served without contacting Google"`
+	MaxEntries         int  `json:"maxEntries" title:"Max Entries" default:"1000" minimum:"1" description:"Least recently used responses are evicted above this limit"`
+	StrictCacheControl bool `json:"strictCacheControl" title:"Strict Cache-Control" description:"Honour max-age and no-cache response directives. Most Google APIs send max-age=0, so entries are revalidated with ETag on every call instead of being served from cache"`
+}
+
+// cacheEntry is a stored successful response
+type cacheEntry struct {
+	key          string
+	response     Response
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// responseCache is an LRU cache of API responses with TTL and ETag revalidation
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+
+	ttl                time.Duration
+	maxEntries         int
+	strictCacheControl bool
+}
+
+func newResponseCache(settings CacheSettings) *responseCache {
+	rc := &responseCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+	rc.configure(settings)
+	return rc
+}
+
+// configure applies new settings keeping already cached entries
+func (rc *responseCache) configure(settings CacheSettings) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	ttl := settings.TTL
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	maxEntries := settings.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+
+	rc.ttl = time.Duration(ttl) * time.Second
+	rc.maxEntries = maxEntries
+	rc.strictCacheControl = settings.StrictCacheControl
+	rc.evict()
+}
+
+// key builds a cache key from service, method, normalized parameters and principal
+func (rc *responseCache) key(serviceID, methodName, principal string, req Request) string {
+	params := make(map[string]any, len(req.Parameters.Data))
+	for k, v := range req.Parameters.Data {
+		if v == nil || v == "" {
+			continue
+		}
+		params[k] = v
+	}
+	// map keys are marshaled sorted, so equal parameters produce equal keys
+	paramsJSON, _ := json.Marshal(params)
+
+	h := sha256.New()
+	for _, part := range []string{serviceID, methodName, principal, string(paramsJSON)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// do serves the request from cache when possible, otherwise sends it
+// (conditionally if the stale entry has validators) and stores the result
+func (rc *responseCache) do(key string, req *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	now := time.Now()
+
+	entry := rc.get(key)
+	if entry != nil && now.Before(entry.expires) {
+		return entry.cached(), nil
+	}
+
+	if entry != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		// still valid, refresh freshness using the new headers
+		revalidated := *entry
+		if ttl, store := rc.freshness(resp.Headers); store {
+			revalidated.expires = now.Add(ttl)
+			rc.put(&revalidated)
+		}
+		return revalidated.cached(), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	ttl, store := rc.freshness(resp.Headers)
+	if !store {
+		rc.remove(key)
+		return resp, nil
+	}
+
+	rc.put(&cacheEntry{
+		key:          key,
+		response:     copyResponse(*resp),
+		etag:         headerString(resp.Headers, "Etag"),
+		lastModified: headerString(resp.Headers, "Last-Modified"),
+		expires:      now.Add(ttl),
+	})
+	return resp, nil
+}
+
+// freshness returns how long a response may be served without revalidation
+// and whether it may be stored at all
+func (rc *responseCache) freshness(headers map[string]any) (time.Duration, bool) {
+	rc.mu.Lock()
+	ttl, strict := rc.ttl, rc.strictCacheControl
+	rc.mu.Unlock()
+
+	for _, directive := range strings.Split(headerString(headers, "Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case !strict:
+			continue
+		case directive == "no-cache":
+			ttl = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && time.Duration(seconds)*time.Second < ttl {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return ttl, true
+}
+
+func (rc *responseCache) get(key string) *cacheEntry {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	rc.order.MoveToFront(el)
+	return el.Value.(*cacheEntry)
+}
+
+func (rc *responseCache) put(entry *cacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if el, ok := rc.entries[entry.key]; ok {
+		el.Value = entry
+		rc.order.MoveToFront(el)
+		return
+	}
+	rc.entries[entry.key] = rc.order.PushFront(entry)
+	rc.evict()
+}
+
+func (rc *responseCache) remove(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if el, ok := rc.entries[key]; ok {
+		rc.order.Remove(el)
+		delete(rc.entries, key)
+	}
+}
+
+// evict drops least recently used entries above the limit, must be called with lock held
+func (rc *responseCache) evict() {
+	for rc.order.Len() > rc.maxEntries {
+		el := rc.order.Back()
+		rc.order.Remove(el)
+		delete(rc.entries, el.Value.(*cacheEntry).key)
+	}
+}
+
+// cached returns a copy of the stored response flagged as served from cache
+func (e *cacheEntry) cached() *Response {
+	resp := copyResponse(e.response)
+	resp.Cached = true
+	return &resp
+}
+
+// copyResponse deep copies the body and headers, so messages built from a response
+// never share maps or slices with the cache
+func copyResponse(resp Response) Response {
+	resp.Headers, _ = copyValue(resp.Headers).(map[string]any)
+	resp.Body.Data, _ = copyValue(resp.Body.Data).(map[string]any)
+	return resp
+}
+
+// copyValue deep copies decoded JSON values
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[k] = copyValue(val)
+		}
+		return m
+	case []any:
+		if v == nil {
+			return v
+		}
+		s := make([]any, len(v))
+		for i, val := range v {
+			s[i] = copyValue(val)
+		}
+		return s
+	case []string:
+		return append([]string(nil), v...)
+	}
+	return v
+}
+
+func headerString(headers map[string]any, name string) string {
+	switch v := headers[name].(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return ""
+}
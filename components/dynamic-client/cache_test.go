@@ -0,0 +1,185 @@
+package dynamicclient
+
+import (
+	"net/http"
+	"testing"
+)
+
+// origin answers like a Google API, counting requests and honouring If-None-Match
+type origin struct {
+	calls        int
+	etag         string
+	cacheControl string
+	status       int
+	lastRequest  *http.Request
+}
+
+func (o *origin) send(req *http.Request) (*Response, error) {
+	o.calls++
+	o.lastRequest = req
+	headers := map[string]any{"Content-Type": "application/json"}
+	if o.etag != "" {
+		headers["Etag"] = o.etag
+	}
+	if o.cacheControl != "" {
+		headers["Cache-Control"] = o.cacheControl
+	}
+	if o.etag != "" && req.Header.Get("If-None-Match") == o.etag {
+		return &Response{StatusCode: http.StatusNotModified, Headers: headers}, nil
+	}
+	status := o.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &Response{
+		StatusCode: status,
+		Headers:    headers,
+		Body: ResponseBody{DynamicSchema{Data: map[string]any{
+			"summary": "Team",
+			"items":   []any{map[string]any{"id": "e1"}},
+		}}},
+	}, nil
+}
+
+func newGet(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "https://www.googleapis.com/calendar/v3/calendars/primary/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings CacheSettings
+		origin   origin
+		calls    int
+		cached   bool
+	}{
+		{name: "fresh entry is served from cache", settings: CacheSettings{TTL: 60}, calls: 1, cached: true},
+		{name: "max-age=0 is ignored by default", settings: CacheSettings{TTL: 60}, origin: origin{cacheControl: "private, max-age=0"}, calls: 1, cached: true},
+		{name: "no-store is never cached", settings: CacheSettings{TTL: 60}, origin: origin{cacheControl: "no-store"}, calls: 2},
+		{name: "errors are not cached", settings: CacheSettings{TTL: 60}, origin: origin{status: http.StatusNotFound}, calls: 2},
+		{
+			name:     "strict max-age=0 revalidates with the etag",
+			settings: CacheSettings{TTL: 60, StrictCacheControl: true},
+			origin:   origin{cacheControl: "private, max-age=0", etag: `"v1"`},
+			calls:    2,
+			cached:   true,
+		},
+		{
+			name:     "strict max-age=0 without validators fetches again",
+			settings: CacheSettings{TTL: 60, StrictCacheControl: true},
+			origin:   origin{cacheControl: "private, max-age=0"},
+			calls:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newResponseCache(tt.settings)
+			o := tt.origin
+			key := rc.key("calendar:v3", "events.list", "principal", Request{})
+
+			if _, err := rc.do(key, newGet(t), o.send); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := rc.do(key, newGet(t), o.send)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if o.calls != tt.calls {
+				t.Fatalf("origin called %d times, want %d", o.calls, tt.calls)
+			}
+			if resp.Cached != tt.cached {
+				t.Fatalf("cached %v, want %v", resp.Cached, tt.cached)
+			}
+			if o.etag != "" && o.lastRequest.Header.Get("If-None-Match") != o.etag {
+				t.Fatalf("revalidation sent If-None-Match %q, want %q", o.lastRequest.Header.Get("If-None-Match"), o.etag)
+			}
+			if tt.cached && (resp.StatusCode != http.StatusOK || resp.Body.Data["summary"] != "Team") {
+				t.Fatalf("unexpected cached response %+v", resp)
+			}
+		})
+	}
+}
+
+func TestResponseCacheCopies(t *testing.T) {
+	rc := newResponseCache(CacheSettings{TTL: 60})
+	o := &origin{}
+	key := rc.key("calendar:v3", "events.list", "principal", Request{})
+
+	first, err := rc.do(key, newGet(t), o.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// downstream changes of the stored response
+	first.Body.Data["summary"] = "changed"
+	first.Body.Data["items"].([]any)[0].(map[string]any)["id"] = "changed"
+	first.Headers["Content-Type"] = "changed"
+
+	hit, err := rc.do(key, newGet(t), o.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// downstream changes of a cache hit
+	hit.Body.Data["items"].([]any)[0].(map[string]any)["id"] = "changed"
+
+	again, err := rc.do(key, newGet(t), o.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Body.Data["summary"] != "Team" || again.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("cache was changed through a response: %+v", again)
+	}
+	if id := again.Body.Data["items"].([]any)[0].(map[string]any)["id"]; id != "e1" {
+		t.Fatalf("cache was changed through a response, item id %v", id)
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	rc := newResponseCache(CacheSettings{TTL: 60, MaxEntries: 2})
+	o := &origin{}
+	keys := []string{"a", "b", "c"}
+	for _, key := range keys[:2] {
+		if _, err := rc.do(key, newGet(t), o.send); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a becomes the most recently used, b is evicted by c
+	if _, err := rc.do("a", newGet(t), o.send); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc.do("c", newGet(t), o.send); err != nil {
+		t.Fatal(err)
+	}
+	if rc.get("b") != nil || rc.get("a") == nil || rc.get("c") == nil {
+		t.Fatal("least recently used entry was not evicted")
+	}
+
+	rc.configure(CacheSettings{TTL: 60, MaxEntries: 1})
+	if rc.order.Len() != 1 {
+		t.Fatalf("cache has %d entries after shrinking, want 1", rc.order.Len())
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	rc := newResponseCache(CacheSettings{})
+	req := func(data map[string]any) Request {
+		return Request{Parameters: RequestParams{DynamicSchema{Data: data}}}
+	}
+	base := rc.key("calendar:v3", "events.list", "alice", req(map[string]any{"calendarId": "primary", "maxResults": 10}))
+
+	if rc.key("calendar:v3", "events.list", "alice", req(map[string]any{"maxResults": 10, "calendarId": "primary", "pageToken": ""})) != base {
+		t.Error("empty parameters change the key")
+	}
+	if rc.key("calendar:v3", "events.list", "bob", req(map[string]any{"calendarId": "primary", "maxResults": 10})) == base {
+		t.Error("principals share a key")
+	}
+	if rc.key("calendar:v3", "events.get", "alice", req(map[string]any{"calendarId": "primary", "maxResults": 10})) == base {
+		t.Error("methods share a key")
+	}
+}
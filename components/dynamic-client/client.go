@@ -3,6 +3,7 @@ package dynamicclient
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,7 +14,13 @@ import (
 
 	"github.com/goccy/go-json"
 	"github.com/rs/zerolog/log"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/apischema"
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
 	"github.com/tiny-systems/googleapis-module/pkg/discovery"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
@@ -23,14 +30,25 @@ const (
 	ComponentName = "google_api_call"
 	RequestPort   = "request"
 	ResponsePort  = "response"
+	ItemPort      = "item"
 	ErrorPort     = "error"
+
+	TokenRefreshedPort = "tokenRefreshed"
 )
 
 // Settings holds the component configuration
 type Settings struct {
-	Service         ServiceName `json:"service" title:"Service" description:"Select a Google API service then save settings" tab:"API Selection"`
-	Method          MethodName  `json:"method" title:"Method" description:"Select an API method" tab:"API Selection"`
-	EnableErrorPort bool        `json:"enableErrorPort" required:"true" title:"Enable Error Port" tab:"General" description:"If request fails, error port will emit an error message"`
+	Service                  ServiceName        `json:"service" title:"Service" description:"Select a Google API service then save settings" tab:"API Selection"`
+	Method                   MethodName         `json:"method" title:"Method" description:"Select an API method" tab:"API Selection"`
+	Auth                     AuthSettings       `json:"auth" title:"Authentication" tab:"Authentication"`
+	EnableTokenRefreshedPort bool               `json:"enableTokenRefreshedPort" title:"Enable Token Refreshed Port" tab:"Authentication" description:"In client credentials mode, emit OAuth tokens renewed during the call so flows can store them"`
+	EnableErrorPort          bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" tab:"General" description:"If request fails, error port will emit an error message"`
+	SplitOutput              bool               `json:"splitOutput" title:"Split Output" tab:"General" description:"For list methods, emit every element of the list on the item port followed by a summary on the response port"`
+	StatusRoutes             []StatusRoute      `json:"statusRoutes" title:"Status Routes" tab:"Routing" description:"Send responses with matching status codes to extra output ports instead of response or error"`
+	RequestID                RequestIDSettings  `json:"requestId" title:"Request ID" tab:"Idempotency"`
+	Cache                    CacheSettings      `json:"cache" title:"Response Cache" tab:"Cache"`
+	RateLimit                ratelimit.Settings `json:"rateLimit" title:"Rate Limit" tab:"Rate Limit"`
+	Cassette                 cassette.Settings  `json:"cassette" title:"Cassette" tab:"Testing"`
 }
 
 // Token represents an OAuth2 access token
@@ -53,9 +71,10 @@ type ResponseBody struct {
 
 // Request represents the input to the component
 type Request struct {
-	Context    any           `json:"context,omitempty" configurable:"true" title:"Context" description:"Arbitrary context to pass through"`
-	Token      Token         `json:"token" required:"true" title:"Token" description:"OAuth2 token for authentication"`
-	Parameters RequestParams `json:"parameters" configurable:"true" title:"Parameters" description:"Request parameters based on selected API method"`
+	Context    any              `json:"context,omitempty" configurable:"true" title:"Context" description:"Arbitrary context to pass through"`
+	Token      Token            `json:"token,omitempty" title:"Token" description:"OAuth2 token, used in OAuth token mode and with OAuth2 client credentials"`
+	Config     etc.ClientConfig `json:"config,omitempty" title:"Client Config" description:"Credentials or API key, used in client credentials and API key modes"`
+	Parameters RequestParams    `json:"parameters" configurable:"true" title:"Parameters" description:"Request parameters based on selected API method"`
 }
 
 // Response represents the successful output
@@ -64,13 +83,54 @@ type Response struct {
 	StatusCode int            `json:"statusCode" title:"Status Code"`
 	Headers    map[string]any `json:"headers,omitempty" title:"Response Headers"`
 	Body       ResponseBody   `json:"body" title:"Response Body" description:"Response data based on selected API method"`
+	Cached     bool           `json:"cached" title:"Cached" description:"Response was served from cache"`
+	RequestID  string         `json:"requestId,omitempty" title:"Request ID" description:"Idempotency key sent with the request"`
+	Token      *Token         `json:"token,omitempty" title:"Token" description:"Current OAuth token in client credentials mode, renewed if it had expired"`
+
+	// tokenRefreshed is set if Token differs from the request token
+	tokenRefreshed bool
+}
+
+// Item is a single list element emitted in split output mode
+type Item struct {
+	Context any      `json:"context,omitempty" title:"Context"`
+	Index   int      `json:"index" title:"Index" description:"Position of the element within the page"`
+	Item    ItemBody `json:"item" title:"Item" description:"List element based on selected API method"`
+}
+
+// Summary is emitted after all items in split output mode
+type Summary struct {
+	Context       any    `json:"context,omitempty" title:"Context"`
+	StatusCode    int    `json:"statusCode" title:"Status Code"`
+	Field         string `json:"field" title:"Field" description:"Response field the items were taken from"`
+	Count         int    `json:"count" title:"Count" description:"Number of items emitted"`
+	NextPageToken string `json:"nextPageToken,omitempty" title:"Next Page Token" description:"Token of the next page, empty on the last page"`
+	Cached        bool   `json:"cached" title:"Cached" description:"Response was served from cache"`
+	RequestID     string `json:"requestId,omitempty" title:"Request ID" description:"Idempotency key sent with the request"`
+	Token         *Token `json:"token,omitempty" title:"Token" description:"Current OAuth token in client credentials mode, renewed if it had expired"`
+}
+
+// TokenRefreshed carries a token renewed during the call
+type TokenRefreshed struct {
+	Context any   `json:"context,omitempty" title:"Context"`
+	Token   Token `json:"token" title:"Token"`
 }
 
 // Error represents an error output
 type Error struct {
-	Context any    `json:"context,omitempty" title:"Context"`
-	Error   string `json:"error" title:"Error Message"`
-	Code    int    `json:"code,omitempty" title:"Error Code"`
+	Context any                    `json:"context,omitempty" title:"Context"`
+	Error   string                 `json:"error" title:"Error Message"`
+	Code    int                    `json:"code,omitempty" title:"Error Code"`
+	Fields  []apischema.FieldError `json:"fields,omitempty" title:"Invalid Fields" description:"Parameters which failed validation against the method definition"`
+}
+
+// APIError is returned for non-2xx responses which are not routed to a port
+type APIError struct {
+	Response *Response
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %v", e.Response.StatusCode, e.Response.Body.Data)
 }
 
 // Component implements the Google API client
@@ -95,6 +155,13 @@ type Component struct {
 	// Request/Response schemas (dynamic)
 	requestSchema  DynamicSchema
 	responseSchema DynamicSchema
+
+	// Repeated resource field of list methods and its element schema, used in split output mode
+	listField  string
+	itemSchema ItemBody
+
+	// Response cache, nil when caching is disabled
+	cache *responseCache
 }
 
 // Instance creates a new component instance
@@ -103,6 +170,16 @@ func (c *Component) Instance() module.Component {
 		settings: Settings{
 			Service: ServiceName{Enum{Value: "", Options: []string{}, Labels: []string{}}},
 			Method:  MethodName{Enum{Value: "", Options: []string{}, Labels: []string{}}},
+			Cache: CacheSettings{
+				TTL:        defaultCacheTTL,
+				MaxEntries: defaultCacheMaxEntries,
+			},
+			Auth: AuthSettings{
+				Mode: AuthToken,
+			},
+			Cassette: cassette.Settings{
+				Mode: cassette.ModeEnv,
+			},
 		},
 		discoveryClient:   discovery.NewClient(),
 		servicesAvailable: []string{},
@@ -144,6 +221,9 @@ func (c *Component) handleSettings(ctx context.Context, msg interface{}) error {
 	if !ok {
 		return fmt.Errorf("invalid settings message")
 	}
+	if err := validateRoutes(in.StatusRoutes); err != nil {
+		return err
+	}
 
 	c.settingsLock.Lock()
 	defer c.settingsLock.Unlock()
@@ -200,6 +280,23 @@ func (c *Component) handleSettings(ctx context.Context, msg interface{}) error {
 
 	// Update other settings
 	c.settings.EnableErrorPort = in.EnableErrorPort
+	c.settings.SplitOutput = in.SplitOutput
+	c.settings.StatusRoutes = in.StatusRoutes
+	c.settings.RequestID = in.RequestID
+	c.settings.Cache = in.Cache
+	c.settings.RateLimit = in.RateLimit
+	c.settings.Auth = in.Auth
+	c.settings.EnableTokenRefreshedPort = in.EnableTokenRefreshedPort
+	c.settings.Cassette = in.Cassette
+
+	switch {
+	case !in.Cache.Enabled:
+		c.cache = nil
+	case c.cache == nil:
+		c.cache = newResponseCache(in.Cache)
+	default:
+		c.cache.configure(in.Cache)
+	}
 
 	// If method selected, build dynamic schemas
 	// Use in.Method.Value since c.settings.Method.Value may have been reset
@@ -235,12 +332,14 @@ func (c *Component) handleRequest(ctx context.Context, handler module.Handler, m
 	}
 
 	c.settingsLock.RLock()
-	serviceID := c.settings.Service.Value
-	methodName := c.settings.Method.Value
-	enableErrorPort := c.settings.EnableErrorPort
+	settings := c.settings
+	cache := c.cache
+	listField := c.listField
 	c.settingsLock.RUnlock()
 
-	if serviceID == "" || methodName == "" {
+	enableErrorPort := settings.EnableErrorPort
+
+	if settings.Service.Value == "" || settings.Method.Value == "" {
 		err := fmt.Errorf("service and method must be selected in settings")
 		if enableErrorPort {
 			return handler(ctx, ErrorPort, Error{
@@ -252,19 +351,80 @@ func (c *Component) handleRequest(ctx context.Context, handler module.Handler, m
 	}
 
 	// Execute the request
-	response, err := c.executeRequest(ctx, serviceID, methodName, in)
+	response, err := c.executeRequest(ctx, settings, cache, &in)
+
+	if err == nil && response.tokenRefreshed && settings.EnableTokenRefreshedPort {
+		if r := handler(ctx, TokenRefreshedPort, TokenRefreshed{Context: in.Context, Token: *response.Token}); r.IsErr() {
+			return r
+		}
+	}
+
+	// Routed statuses go to their port, unrouted error statuses to the error port
+	port := ResponsePort
+	if err == nil {
+		response.Context = in.Context
+		routed, ok := routeFor(settings.StatusRoutes, response.StatusCode)
+		switch {
+		case ok:
+			port = routed
+		case response.StatusCode >= 400:
+			err = &APIError{Response: response}
+		}
+	}
 	if err != nil {
-		if enableErrorPort {
-			return handler(ctx, ErrorPort, Error{
-				Context: in.Context,
-				Error:   err.Error(),
-			})
+		if !enableErrorPort {
+			return module.Fail(err)
+		}
+		errMsg := Error{
+			Context: in.Context,
+			Error:   err.Error(),
+		}
+		var validationErr *apischema.ValidationError
+		if errors.As(err, &validationErr) {
+			errMsg.Code = http.StatusBadRequest
+			errMsg.Fields = validationErr.Fields
+		}
+		var rateLimitErr *ratelimit.Error
+		if errors.As(err, &rateLimitErr) {
+			errMsg.Code = http.StatusTooManyRequests
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			errMsg.Code = apiErr.Response.StatusCode
+		}
+		return handler(ctx, ErrorPort, errMsg)
+	}
+
+	if port == ResponsePort && settings.SplitOutput && listField != "" && response.StatusCode < 300 {
+		return emitItems(ctx, handler, listField, response)
+	}
+	return handler(ctx, port, response)
+}
+
+// emitItems sends every element of the list field to the item port followed by a summary
+func emitItems(ctx context.Context, handler module.Handler, field string, response *Response) module.Result {
+	items, _ := response.Body.Data[field].([]any)
+	for i, item := range items {
+		if r := handler(ctx, ItemPort, Item{
+			Context: response.Context,
+			Index:   i,
+			Item:    ItemBody{Data: item},
+		}); r.IsErr() {
+			return r
 		}
-		return module.Fail(err)
 	}
 
-	response.Context = in.Context
-	return handler(ctx, ResponsePort, response)
+	nextPageToken, _ := response.Body.Data["nextPageToken"].(string)
+	return handler(ctx, ResponsePort, Summary{
+		Context:       response.Context,
+		StatusCode:    response.StatusCode,
+		Field:         field,
+		Count:         len(items),
+		NextPageToken: nextPageToken,
+		Cached:        response.Cached,
+		RequestID:     response.RequestID,
+		Token:         response.Token,
+	})
 }
 
 // discoverServices loads available Google API services
@@ -357,6 +517,12 @@ func (c *Component) buildSchemas(ctx context.Context, serviceID, methodName stri
 			converter := NewSchemaConverter(api)
 			c.requestSchema = converter.BuildRequestSchema(m.Method)
 			c.responseSchema = converter.BuildResponseSchema(m.Method)
+
+			c.listField, c.itemSchema = "", ItemBody{}
+			if field, items, ok := apischema.ListField(api, m.Method); ok {
+				c.listField = field
+				c.itemSchema = converter.BuildItemSchema(items)
+			}
 			c.currentMethod = &m
 
 			// Log schema properties to debug
@@ -385,83 +551,149 @@ func (c *Component) buildSchemas(ctx context.Context, serviceID, methodName stri
 	return fmt.Errorf("method %s not found", methodName)
 }
 
-// executeRequest makes the actual HTTP request to the Google API
-func (c *Component) executeRequest(ctx context.Context, serviceID, methodName string, req Request) (*Response, error) {
+// executeRequest makes the actual HTTP request to the Google API.
+// The context of in gets the generated requestId, so outputs carry it.
+func (c *Component) executeRequest(ctx context.Context, settings Settings, cache *responseCache, in *Request) (*Response, error) {
+	req := *in
+	serviceID, methodName := settings.Service.Value, settings.Method.Value
+
+	// Record or replay discovery and API calls if enabled
+	ctx = cassette.WithSettings(ctx, settings.Cassette)
+
 	api, err := c.discoveryClient.GetAPI(ctx, serviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get API spec: %w", err)
 	}
 
-	// Find the method
-	methods := api.GetAllMethods()
-	var methodInfo *struct {
-		FullName string
-		Method   interface{}
-		Resource string
+	methodData, ok := findMethod(api, methodName)
+	if !ok {
+		return nil, fmt.Errorf("method %s not found", methodName)
 	}
 
-	for _, m := range methods {
-		if m.FullName == methodName {
-			methodInfo = &struct {
-				FullName string
-				Method   interface{}
-				Resource string
-			}{m.FullName, m.Method, m.Resource}
-			break
+	// Fill requestId unless given explicitly, retries of the same message reuse it
+	var reqID string
+	if settings.RequestID.Enabled && acceptsRequestID(api, methodData) {
+		if given, ok := req.Parameters.Data[requestIDField].(string); ok && given != "" {
+			reqID = given
+		} else {
+			if reqID, req.Context, err = requestID(serviceID, methodName, settings.RequestID, req); err != nil {
+				return nil, err
+			}
+			in.Context = req.Context
+			params := make(map[string]any, len(req.Parameters.Data)+1)
+			for k, v := range req.Parameters.Data {
+				params[k] = v
+			}
+			params[requestIDField] = reqID
+			req.Parameters = RequestParams{DynamicSchema{Data: params}}
 		}
 	}
 
-	if methodInfo == nil {
-		return nil, fmt.Errorf("method %s not found", methodName)
+	// Validate parameters before hitting the network
+	if err := apischema.ValidateParameters(api, methodData, req.Parameters.Data); err != nil {
+		return nil, err
 	}
 
-	// Get method details - need to re-fetch to get typed access
-	var foundMethod interface{}
-	for _, m := range methods {
-		if m.FullName == methodName {
-			foundMethod = m.Method
-			break
+	// Spans and metrics of the outgoing request carry the service and method
+	ctx = telemetry.WithCall(ctx, serviceID, methodName)
+
+	httpReq, err := newHTTPRequest(ctx, api, methodData, req)
+	if err != nil {
+		return nil, err
+	}
+
+	client, release, err := newHTTPClient(ctx, settings.Auth, methodData, req)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	caller := principal(settings.Auth, req)
+	limitKey := caller
+	if settings.RateLimit.Enabled && settings.RateLimit.Scope == ratelimit.ScopeProject {
+		if limitKey, err = etc.QuotaProject(ctx, req.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Cache hits don't count against the rate limit
+	send := func(httpReq *http.Request) (*Response, error) {
+		if err := ratelimit.Wait(ctx, serviceID, limitKey, settings.RateLimit); err != nil {
+			return nil, err
+		}
+		return sendRequest(client, httpReq)
+	}
+
+	// Only read-only calls are cached
+	var resp *Response
+	if cache == nil || httpReq.Method != http.MethodGet {
+		resp, err = send(httpReq)
+	} else {
+		resp, err = cache.do(cache.key(serviceID, methodName, caller, req), httpReq, send)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.RequestID = reqID
+	if settings.Auth.Mode == AuthCredentials {
+		if current, refreshed := etc.CurrentToken(client, token(req)); current != nil {
+			resp.Token = &Token{
+				AccessToken:  current.AccessToken,
+				TokenType:    current.TokenType,
+				RefreshToken: current.RefreshToken,
+				Expiry:       current.Expiry,
+			}
+			resp.tokenRefreshed = refreshed
 		}
 	}
+	return resp, nil
+}
 
+// newHTTPRequest builds the HTTP request for a method from request parameters
+func newHTTPRequest(ctx context.Context, api *googleapismodule.API, methodData googleapismodule.Method, req Request) (*http.Request, error) {
 	// Build the request URL
 	baseURL := api.BaseUrl
 	if baseURL == "" {
 		baseURL = api.RootUrl + api.ServicePath
 	}
 
-	// Get method path and parameters
-	methodData := methods[0].Method // We need the actual method
-	for _, m := range methods {
-		if m.FullName == methodName {
-			methodData = m.Method
-			break
-		}
-	}
-
 	// Use flatPath if available, otherwise path
 	path := methodData.FlatPath
 	if path == "" {
 		path = methodData.Path
 	}
 
-	// Build query parameters and substitute path parameters
+	httpMethod := methodData.HttpMethod
+	hasBody := httpMethod == http.MethodPost || httpMethod == http.MethodPut || httpMethod == http.MethodPatch
+
+	// Build query parameters, substitute path parameters and collect body fields
 	queryParams := url.Values{}
 	pathParams := make(map[string]string)
+	bodyData := make(map[string]any)
+
+	for name, value := range req.Parameters.Data {
+		if value == nil {
+			continue
+		}
+		param, hasParam := methodData.Parameters[name]
+		if !hasParam {
+			// Standard parameters shared by all methods (fields, alt, quotaUser...)
+			param, hasParam = api.Parameters[name]
+		}
 
-	if req.Parameters.Data != nil {
-		for name, value := range req.Parameters.Data {
-			strValue := fmt.Sprintf("%v", value)
-			param, hasParam := methodData.Parameters[name]
-
-			if hasParam && param.Location == "path" {
-				pathParams[name] = strValue
-			} else if hasParam && param.Location == "query" {
-				queryParams.Set(name, strValue)
-			} else {
-				// Unknown parameter, add to query (for body fields we'll handle separately)
-				queryParams.Set(name, strValue)
+		switch {
+		case hasParam && param.Location == "path":
+			pathParams[name] = fmt.Sprintf("%v", value)
+		case hasParam && param.Location == "query", !hasBody:
+			// Unknown parameters of body-less methods go to query as well (e.g. fields, alt)
+			if values, ok := value.([]any); ok {
+				for _, v := range values {
+					queryParams.Add(name, fmt.Sprintf("%v", v))
+				}
+				continue
 			}
+			queryParams.Set(name, fmt.Sprintf("%v", value))
+		default:
+			bodyData[name] = value
 		}
 	}
 
@@ -479,26 +711,12 @@ func (c *Component) executeRequest(ctx context.Context, serviceID, methodName st
 
 	// Prepare request body for POST/PUT/PATCH
 	var bodyReader io.Reader
-	httpMethod := methodData.HttpMethod
-	if httpMethod == "POST" || httpMethod == "PUT" || httpMethod == "PATCH" {
-		// Build body from parameters that aren't path/query
-		bodyData := make(map[string]any)
-		if req.Parameters.Data != nil {
-			for name, value := range req.Parameters.Data {
-				param, hasParam := methodData.Parameters[name]
-				if !hasParam || (param.Location != "path" && param.Location != "query") {
-					bodyData[name] = value
-				}
-			}
-		}
-
-		if len(bodyData) > 0 {
-			jsonBody, err := json.Marshal(bodyData)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal request body: %w", err)
-			}
-			bodyReader = bytes.NewReader(jsonBody)
+	if hasBody && len(bodyData) > 0 {
+		jsonBody, err := json.Marshal(bodyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+		bodyReader = bytes.NewReader(jsonBody)
 	}
 
 	// Create HTTP request
@@ -508,12 +726,15 @@ func (c *Component) executeRequest(ctx context.Context, serviceID, methodName st
 	}
 
 	// Set headers
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", req.Token.AccessToken))
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json")
 
-	// Execute request
-	client := &http.Client{Timeout: 30 * time.Second}
+	return httpReq, nil
+}
+
+// sendRequest executes the HTTP request and decodes the response,
+// error statuses are returned as responses so they can be routed
+func sendRequest(client *http.Client, httpReq *http.Request) (*Response, error) {
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -545,13 +766,6 @@ func (c *Component) executeRequest(ctx context.Context, serviceID, methodName st
 		}
 	}
 
-	// Check for error status
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %v", resp.StatusCode, bodyData)
-	}
-
-	_ = foundMethod // silence unused warning
-
 	// Convert body to ResponseBody
 	var responseBody ResponseBody
 	if bodyMap, ok := bodyData.(map[string]any); ok {
@@ -568,6 +782,16 @@ func (c *Component) executeRequest(ctx context.Context, serviceID, methodName st
 	}, nil
 }
 
+// findMethod looks up a method by its full name
+func findMethod(api *googleapismodule.API, methodName string) (googleapismodule.Method, bool) {
+	for _, m := range api.GetAllMethods() {
+		if m.FullName == methodName {
+			return m.Method, true
+		}
+	}
+	return googleapismodule.Method{}, false
+}
+
 // Ports returns the component's port configuration
 func (c *Component) Ports() []module.Port {
 	c.settingsLock.RLock()
@@ -596,7 +820,15 @@ func (c *Component) Ports() []module.Port {
 				Labels:  c.methodsLabels,
 			},
 		},
-		EnableErrorPort: c.settings.EnableErrorPort,
+		Auth:                     c.settings.Auth,
+		EnableTokenRefreshedPort: c.settings.EnableTokenRefreshedPort,
+		EnableErrorPort:          c.settings.EnableErrorPort,
+		SplitOutput:              c.settings.SplitOutput,
+		StatusRoutes:             c.settings.StatusRoutes,
+		RequestID:                c.settings.RequestID,
+		Cache:                    c.settings.Cache,
+		RateLimit:                c.settings.RateLimit,
+		Cassette:                 c.settings.Cassette,
 	}
 
 	ports := []module.Port{
@@ -613,7 +845,26 @@ func (c *Component) Ports() []module.Port {
 				Parameters: RequestParams{c.requestSchema},
 			},
 		},
-		{
+	}
+
+	if c.settings.SplitOutput && c.listField != "" {
+		ports = append(ports, module.Port{
+			Name:     ItemPort,
+			Label:    "Item",
+			Position: module.Right,
+			Source:   true,
+			Configuration: Item{
+				Item: c.itemSchema,
+			},
+		}, module.Port{
+			Name:          ResponsePort,
+			Label:         "Summary",
+			Position:      module.Right,
+			Source:        true,
+			Configuration: Summary{Field: c.listField},
+		})
+	} else {
+		ports = append(ports, module.Port{
 			Name:     ResponsePort,
 			Label:    "Response",
 			Position: module.Right,
@@ -621,7 +872,35 @@ func (c *Component) Ports() []module.Port {
 			Configuration: Response{
 				Body: ResponseBody{c.responseSchema},
 			},
-		},
+		})
+	}
+
+	names, successOnly := routePorts(c.settings.StatusRoutes)
+	for _, name := range names {
+		// error bodies don't follow the method response schema
+		body := ResponseBody{}
+		if successOnly[name] {
+			body = ResponseBody{c.responseSchema}
+		}
+		ports = append(ports, module.Port{
+			Name:     name,
+			Label:    name,
+			Position: module.Right,
+			Source:   true,
+			Configuration: Response{
+				Body: body,
+			},
+		})
+	}
+
+	if c.settings.EnableTokenRefreshedPort {
+		ports = append(ports, module.Port{
+			Name:          TokenRefreshedPort,
+			Label:         "Token Refreshed",
+			Position:      module.Right,
+			Source:        true,
+			Configuration: TokenRefreshed{},
+		})
 	}
 
 	if c.settings.EnableErrorPort {
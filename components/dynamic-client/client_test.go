@@ -0,0 +1,70 @@
+package dynamicclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/module/module"
+)
+
+func TestEmitItems(t *testing.T) {
+	response := &Response{
+		Context:    "ctx",
+		StatusCode: 200,
+		Cached:     true,
+		RequestID:  "request-id",
+		Body: ResponseBody{DynamicSchema{Data: map[string]any{
+			"values":        []any{map[string]any{"id": "e1"}, "label", float64(3), nil},
+			"nextPageToken": "next",
+		}}},
+	}
+
+	var items []Item
+	var summary Summary
+	handler := func(_ context.Context, port string, data any) module.Result {
+		switch port {
+		case ItemPort:
+			items = append(items, data.(Item))
+		case ResponsePort:
+			summary = data.(Summary)
+		default:
+			t.Fatalf("unexpected port %s", port)
+		}
+		return module.Ok(nil)
+	}
+
+	if r := emitItems(context.Background(), handler, "values", response); r.IsErr() {
+		t.Fatal(r.Err())
+	}
+
+	got, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"context":"ctx","index":0,"item":{"id":"e1"}},{"context":"ctx","index":1,"item":"label"},{"context":"ctx","index":2,"item":3},{"context":"ctx","index":3,"item":null}]`
+	if string(got) != want {
+		t.Fatalf("items %s, want %s", got, want)
+	}
+
+	wantSummary := Summary{Context: "ctx", StatusCode: 200, Field: "values", Count: 4, NextPageToken: "next", Cached: true, RequestID: "request-id"}
+	if summary != wantSummary {
+		t.Fatalf("summary %+v, want %+v", summary, wantSummary)
+	}
+}
+
+func TestBuildItemSchemaScalar(t *testing.T) {
+	api := &googleapismodule.API{}
+	item := NewSchemaConverter(api).BuildItemSchema(googleapismodule.Schema{Type: "string"})
+	if _, ok := item.Data.(string); !ok {
+		t.Fatalf("example of a string element is %T", item.Data)
+	}
+	schema, err := item.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Type == nil || schema.Type.SimpleTypes == nil || *schema.Type.SimpleTypes != "string" {
+		t.Fatalf("unexpected schema %+v", schema)
+	}
+}
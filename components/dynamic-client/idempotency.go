@@ -0,0 +1,96 @@
+package dynamicclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+const requestIDField = "requestId"
+
+// requestIDNamespace scopes generated request IDs to this module
+var requestIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/tiny-systems/googleapis-module/requestId"))
+
+// RequestIDSettings configures idempotency keys for methods accepting a requestId
+type RequestIDSettings struct {
+	Enabled      bool   `json:"enabled" title:"Generate Request ID" description:"Fill the requestId parameter of methods which accept one. Every message gets a random ID, stored as requestId in the message context, so retries passing the context back don't create duplicate resources"`
+	ContextField string `json:"contextField,omitempty" title:"Context Field" description:"Dot separated path to a unique value in the message context, e.g. order.id, to derive the ID from instead of generating one"`
+	FromContent  bool   `json:"fromContent,omitempty" title:"Derive From Content" description:"Derive the ID from the whole context and parameters instead of generating one, so redelivered messages get the same ID. Separate messages with equal context and parameters get the same ID too, Google drops all but the first as duplicates"`
+}
+
+// acceptsRequestID reports whether a method has a requestId query or body parameter
+func acceptsRequestID(api *googleapismodule.API, method googleapismodule.Method) bool {
+	if _, ok := method.Parameters[requestIDField]; ok {
+		return true
+	}
+	if method.Request == nil || method.Request.Ref == "" {
+		return false
+	}
+	_, ok := api.Schemas[method.Request.Ref].Properties[requestIDField]
+	return ok
+}
+
+// requestID returns the requestId of a message and its context. By default a random UUID is generated
+// and stored in the context, the ID stored in the context of a retried message is reused.
+// IDs derived from a context field or the content are stable without storing them.
+func requestID(serviceID, methodName string, settings RequestIDSettings, req Request) (string, any, error) {
+	switch {
+	case settings.ContextField != "":
+		value, ok := lookupPath(req.Context, settings.ContextField)
+		if !ok || value == nil || value == "" {
+			return "", nil, fmt.Errorf("context field %s is required to generate requestId", settings.ContextField)
+		}
+		id, err := derivedRequestID(serviceID, methodName, value)
+		return id, req.Context, err
+	case settings.FromContent:
+		id, err := derivedRequestID(serviceID, methodName, map[string]any{
+			"context":    req.Context,
+			"parameters": req.Parameters.Data,
+		})
+		return id, req.Context, err
+	}
+
+	values, isMap := req.Context.(map[string]any)
+	if id, ok := values[requestIDField].(string); ok && id != "" {
+		return id, req.Context, nil
+	}
+	id := uuid.NewString()
+	if req.Context != nil && !isMap {
+		// only object contexts can carry the ID
+		return id, req.Context, nil
+	}
+	withID := make(map[string]any, len(values)+1)
+	for k, v := range values {
+		withID[k] = v
+	}
+	withID[requestIDField] = id
+	return id, withID, nil
+}
+
+// derivedRequestID derives a stable UUID from a value
+func derivedRequestID(serviceID, methodName string, source any) (string, error) {
+	// map keys are marshaled sorted, so equal values produce equal IDs
+	data, err := json.Marshal(source)
+	if err != nil {
+		return "", fmt.Errorf("unable to derive requestId: %w", err)
+	}
+	name := serviceID + "\x00" + methodName + "\x00" + string(data)
+	return uuid.NewSHA1(requestIDNamespace, []byte(name)).String(), nil
+}
+
+// lookupPath finds a value by dot separated path in nested maps
+func lookupPath(value any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
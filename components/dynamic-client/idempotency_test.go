@@ -0,0 +1,118 @@
+package dynamicclient
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+func TestRequestID(t *testing.T) {
+	params := func(data map[string]any) RequestParams {
+		return RequestParams{DynamicSchema{Data: data}}
+	}
+	order := Request{
+		Context:    map[string]any{"order": map[string]any{"id": "o-1"}},
+		Parameters: params(map[string]any{"project": "p", "name": "instance"}),
+	}
+	id := func(settings RequestIDSettings, req Request) (string, any) {
+		t.Helper()
+		id, context, err := requestID("compute:v1", "instances.insert", settings, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			t.Fatalf("request ID %q is not a UUID", id)
+		}
+		return id, context
+	}
+
+	t.Run("random per message", func(t *testing.T) {
+		first, context := id(RequestIDSettings{}, order)
+		second, _ := id(RequestIDSettings{}, order)
+		if first == second {
+			t.Fatal("separate messages with equal content share a request ID")
+		}
+		if context.(map[string]any)[requestIDField] != first {
+			t.Fatalf("request ID is not stored in the context %v", context)
+		}
+		if _, ok := order.Context.(map[string]any)[requestIDField]; ok {
+			t.Fatal("context of the message was modified")
+		}
+
+		// a retry passes the context back
+		retry := order
+		retry.Context = context
+		if again, _ := id(RequestIDSettings{}, retry); again != first {
+			t.Fatalf("retry got request ID %s, want %s", again, first)
+		}
+	})
+
+	t.Run("context without object", func(t *testing.T) {
+		_, context := id(RequestIDSettings{}, Request{})
+		if _, ok := context.(map[string]any)[requestIDField]; !ok {
+			t.Fatalf("request ID is not stored in an empty context %v", context)
+		}
+		if _, context = id(RequestIDSettings{}, Request{Context: "text"}); context != "text" {
+			t.Fatalf("scalar context was replaced by %v", context)
+		}
+	})
+
+	t.Run("from content", func(t *testing.T) {
+		settings := RequestIDSettings{FromContent: true}
+		first, context := id(settings, order)
+		if second, _ := id(settings, order); first != second {
+			t.Fatal("equal content gets different request IDs")
+		}
+		if _, ok := context.(map[string]any)[requestIDField]; ok {
+			t.Fatal("derived request ID is stored in the context")
+		}
+		other := order
+		other.Parameters = params(map[string]any{"project": "p", "name": "other"})
+		if third, _ := id(settings, other); third == first {
+			t.Fatal("different parameters share a request ID")
+		}
+	})
+
+	t.Run("context field", func(t *testing.T) {
+		settings := RequestIDSettings{ContextField: "order.id"}
+		first, _ := id(settings, order)
+		other := order
+		other.Parameters = params(map[string]any{"project": "p", "name": "other"})
+		if second, _ := id(settings, other); first != second {
+			t.Fatal("messages of the same order get different request IDs")
+		}
+		if _, _, err := requestID("compute:v1", "instances.insert", RequestIDSettings{ContextField: "order.number"}, order); err == nil {
+			t.Fatal("expected an error for a missing context field")
+		}
+	})
+}
+
+func TestAcceptsRequestID(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(`{
+		"schemas": {
+			"Instance": {"id": "Instance", "type": "object", "properties": {"name": {"type": "string"}}},
+			"Job": {"id": "Job", "type": "object", "properties": {"requestId": {"type": "string"}}}
+		}
+	}`), &api); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method googleapismodule.Method
+		want   bool
+	}{
+		{name: "query parameter", method: googleapismodule.Method{Parameters: map[string]googleapismodule.Parameter{"requestId": {Type: "string"}}, Request: &googleapismodule.SchemaRef{Ref: "Instance"}}, want: true},
+		{name: "body field", method: googleapismodule.Method{Request: &googleapismodule.SchemaRef{Ref: "Job"}}, want: true},
+		{name: "none", method: googleapismodule.Method{Request: &googleapismodule.SchemaRef{Ref: "Instance"}}},
+		{name: "no body", method: googleapismodule.Method{}},
+	}
+	for _, tt := range tests {
+		if got := acceptsRequestID(&api, tt.method); got != tt.want {
+			t.Errorf("%s: acceptsRequestID = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
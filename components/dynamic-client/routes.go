@@ -0,0 +1,99 @@
+package dynamicclient
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tiny-systems/module/api/v1alpha1"
+)
+
+// StatusRoute sends responses with matching status codes to a dedicated port
+type StatusRoute struct {
+	Status string `json:"status" required:"true" title:"Status" description:"Status code like 404 or class like 4xx"`
+	Port   string `json:"port" required:"true" title:"Port" description:"Output port name, e.g. notFound. Use response to route to the default output"`
+}
+
+var portNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// reservedPorts can't be used as route targets
+var reservedPorts = map[string]bool{
+	v1alpha1.SettingsPort: true,
+	RequestPort:           true,
+	ErrorPort:             true,
+	ItemPort:              true,
+	TokenRefreshedPort:    true,
+}
+
+// validateRoutes checks status patterns and port names
+func validateRoutes(routes []StatusRoute) error {
+	for _, r := range routes {
+		if _, _, ok := parseStatus(r.Status); !ok {
+			return fmt.Errorf("invalid route status %q: use a code like 404 or a class like 4xx", r.Status)
+		}
+		if !portNamePattern.MatchString(r.Port) {
+			return fmt.Errorf("invalid route port name %q", r.Port)
+		}
+		if reservedPorts[r.Port] {
+			return fmt.Errorf("port %q is reserved and can't be used as a route", r.Port)
+		}
+	}
+	return nil
+}
+
+// parseStatus parses "404" into an exact code and "4xx" into a class
+func parseStatus(status string) (code int, class bool, ok bool) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if len(status) != 3 {
+		return 0, false, false
+	}
+	if strings.HasSuffix(status, "xx") {
+		digit := int(status[0] - '0')
+		return digit, true, digit >= 1 && digit <= 5
+	}
+	code, err := strconv.Atoi(status)
+	return code, false, err == nil && code >= 100 && code <= 599
+}
+
+// routeFor returns the port for a status code, exact codes win over classes
+func routeFor(routes []StatusRoute, statusCode int) (string, bool) {
+	port, found := "", false
+	for _, r := range routes {
+		code, class, ok := parseStatus(r.Status)
+		switch {
+		case !ok:
+			continue
+		case !class && code == statusCode:
+			return r.Port, true
+		case class && !found && code == statusCode/100:
+			port, found = r.Port, true
+		}
+	}
+	return port, found
+}
+
+// routePorts returns route port names in a stable order, each with whether
+// it only receives successful responses (so it carries the response schema)
+func routePorts(routes []StatusRoute) ([]string, map[string]bool) {
+	successOnly := make(map[string]bool)
+	for _, r := range routes {
+		code, class, ok := parseStatus(r.Status)
+		if !ok || r.Port == ResponsePort || reservedPorts[r.Port] || !portNamePattern.MatchString(r.Port) {
+			continue
+		}
+		success := (class && code == 2) || (!class && code/100 == 2)
+		if prev, seen := successOnly[r.Port]; seen {
+			success = success && prev
+		}
+		successOnly[r.Port] = success
+	}
+
+	names := make([]string, 0, len(successOnly))
+	for name := range successOnly {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, successOnly
+}
@@ -0,0 +1,94 @@
+package dynamicclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		code   int
+		class  bool
+		ok     bool
+	}{
+		{status: "404", code: 404, ok: true},
+		{status: " 4XX ", code: 4, class: true, ok: true},
+		{status: "2xx", code: 2, class: true, ok: true},
+		{status: "6xx"},
+		{status: "0xx"},
+		{status: "099"},
+		{status: "600"},
+		{status: "40"},
+		{status: "abc"},
+	}
+	for _, tt := range tests {
+		code, class, ok := parseStatus(tt.status)
+		if ok != tt.ok || (ok && (code != tt.code || class != tt.class)) {
+			t.Errorf("parseStatus(%q) = %d, %v, %v, want %d, %v, %v", tt.status, code, class, ok, tt.code, tt.class, tt.ok)
+		}
+	}
+}
+
+func TestRouteFor(t *testing.T) {
+	routes := []StatusRoute{
+		{Status: "4xx", Port: "clientError"},
+		{Status: "404", Port: "notFound"},
+		{Status: "4xx", Port: "shadowed"},
+		{Status: "5xx", Port: "serverError"},
+		{Status: "bad", Port: "ignored"},
+	}
+	tests := []struct {
+		status int
+		port   string
+		found  bool
+	}{
+		// exact codes win over classes listed before them
+		{status: 404, port: "notFound", found: true},
+		// the first matching class wins
+		{status: 429, port: "clientError", found: true},
+		{status: 503, port: "serverError", found: true},
+		{status: 200},
+	}
+	for _, tt := range tests {
+		port, found := routeFor(routes, tt.status)
+		if port != tt.port || found != tt.found {
+			t.Errorf("routeFor(%d) = %q, %v, want %q, %v", tt.status, port, found, tt.port, tt.found)
+		}
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []StatusRoute
+		err    bool
+	}{
+		{name: "valid", routes: []StatusRoute{{Status: "404", Port: "notFound"}, {Status: "2xx", Port: ResponsePort}}},
+		{name: "invalid status", routes: []StatusRoute{{Status: "4x", Port: "notFound"}}, err: true},
+		{name: "invalid port name", routes: []StatusRoute{{Status: "404", Port: "not found"}}, err: true},
+		{name: "reserved port", routes: []StatusRoute{{Status: "500", Port: ErrorPort}}, err: true},
+	}
+	for _, tt := range tests {
+		if err := validateRoutes(tt.routes); (err != nil) != tt.err {
+			t.Errorf("%s: validateRoutes error %v, want error %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRoutePorts(t *testing.T) {
+	names, successOnly := routePorts([]StatusRoute{
+		{Status: "2xx", Port: "ok"},
+		{Status: "201", Port: "created"},
+		{Status: "201", Port: "mixed"},
+		{Status: "404", Port: "mixed"},
+		{Status: "500", Port: ResponsePort},
+		{Status: "500", Port: ErrorPort},
+	})
+	if want := []string{"created", "mixed", "ok"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("ports %v, want %v", names, want)
+	}
+	if want := map[string]bool{"ok": true, "created": true, "mixed": false}; !reflect.DeepEqual(successOnly, want) {
+		t.Fatalf("success only %v, want %v", successOnly, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/swaggest/jsonschema-go"
 	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/pkg/apischema"
 )
 
 // DynamicSchema wraps a dynamically generated schema from Google's discovery format
@@ -33,9 +34,34 @@ func (d DynamicSchema) JSONSchema() (jsonschema.Schema, error) {
 	return schema, nil
 }
 
+// ItemBody holds a single list element, lists of strings or numbers have scalar elements
+type ItemBody struct {
+	Data       any
+	schemaData *jsonschema.Schema
+}
+
+// MarshalJSON serializes the element
+func (b ItemBody) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Data)
+}
+
+// UnmarshalJSON deserializes the element
+func (b *ItemBody) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &b.Data)
+}
+
+// JSONSchema returns the pre-computed schema of the element
+func (b ItemBody) JSONSchema() (jsonschema.Schema, error) {
+	if b.schemaData != nil {
+		return *b.schemaData, nil
+	}
+	return jsonschema.Schema{}, nil
+}
+
 var _ jsonschema.Exposer = (*DynamicSchema)(nil)
 var _ jsonschema.Exposer = (*RequestParams)(nil)
 var _ jsonschema.Exposer = (*ResponseBody)(nil)
+var _ jsonschema.Exposer = (*ItemBody)(nil)
 
 // SchemaConverter converts Google Discovery schemas to JSON schemas
 type SchemaConverter struct {
@@ -63,11 +89,19 @@ func (c *SchemaConverter) BuildRequestSchema(method googleapismodule.Method) Dyn
 	required := make([]string, 0)
 	sampleData := make(map[string]any)
 
+	examples := apischema.NewExampleGenerator(c.api)
+	examples.SkipReadOnly = true
+
 	// Add path and query parameters
 	for name, param := range method.Parameters {
 		propSchema := c.parameterToSchema(param)
 		properties[name] = jsonschema.SchemaOrBool{TypeObject: propSchema}
-		sampleData[name] = nil // Will be filled by user
+
+		// Sample data is sent as is unless user edits it, so only
+		// required parameters and parameters with defaults get examples
+		if param.Required || param.Default != "" {
+			sampleData[name] = examples.Parameter(param)
+		}
 
 		if param.Required {
 			required = append(required, name)
@@ -76,7 +110,6 @@ func (c *SchemaConverter) BuildRequestSchema(method googleapismodule.Method) Dyn
 
 	// Add request body if present
 	if method.Request != nil && method.Request.Ref != "" {
-		sampleData["_requestBodyRef"] = method.Request.Ref
 		if bodySchema, ok := c.api.Schemas[method.Request.Ref]; ok {
 			// Merge body properties into main schema
 			c.visited = make(map[string]bool) // Reset visited for new conversion
@@ -84,7 +117,13 @@ func (c *SchemaConverter) BuildRequestSchema(method googleapismodule.Method) Dyn
 			if bodyJSONSchema.Properties != nil {
 				for name, prop := range bodyJSONSchema.Properties {
 					properties[name] = prop
-					sampleData[name] = nil
+				}
+			}
+			if bodyExample, ok := examples.Schema(bodySchema).(map[string]any); ok {
+				for name, value := range bodyExample {
+					if _, isParam := method.Parameters[name]; !isParam {
+						sampleData[name] = value
+					}
 				}
 			}
 		}
@@ -139,11 +178,12 @@ func (c *SchemaConverter) BuildResponseSchema(method googleapismodule.Method) Dy
 
 	// Convert each property from the response schema
 	c.visited = make(map[string]bool)
+	examples := apischema.NewExampleGenerator(c.api)
 	if responseSchema.Properties != nil {
 		for name, prop := range responseSchema.Properties {
 			propSchema := c.schemaToJSONSchema(prop, 0)
 			properties[name] = jsonschema.SchemaOrBool{TypeObject: propSchema}
-			sampleData[name] = nil
+			sampleData[name] = examples.Schema(prop)
 		}
 	}
 
@@ -157,6 +197,15 @@ func (c *SchemaConverter) BuildResponseSchema(method googleapismodule.Method) Dy
 	}
 }
 
+// BuildItemSchema creates an ItemBody for a single element of a list response field
+func (c *SchemaConverter) BuildItemSchema(items googleapismodule.Schema) ItemBody {
+	c.visited = make(map[string]bool)
+	return ItemBody{
+		Data:       apischema.NewExampleGenerator(c.api).Schema(items),
+		schemaData: c.schemaToJSONSchema(items, 0),
+	}
+}
+
 // parameterToSchema converts a Google API parameter to JSON schema
 func (c *SchemaConverter) parameterToSchema(param googleapismodule.Parameter) *jsonschema.Schema {
 	schema := &jsonschema.Schema{}
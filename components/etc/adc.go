@@ -0,0 +1,139 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// metadataFlavor must be sent with every metadata server request
+const metadataFlavor = "Google"
+
+// ADCTokenSource returns a token source of application default credentials.
+// With a metadata host tokens come straight from that metadata server,
+// otherwise the standard lookup applies: GOOGLE_APPLICATION_CREDENTIALS,
+// gcloud user credentials, then the GCE/GKE metadata server.
+func ADCTokenSource(ctx context.Context, config ClientConfig) (oauth2.TokenSource, error) {
+	if config.MetadataHost != "" {
+		return oauth2.ReuseTokenSource(nil, &metadataTokenSource{
+			ctx:    ctx,
+			host:   config.MetadataHost,
+			scopes: config.Scopes,
+		}), nil
+	}
+	creds, err := google.FindDefaultCredentials(ctx, config.Scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find application default credentials: %v", err)
+	}
+	return creds.TokenSource, nil
+}
+
+// ProjectID returns the configured project, the project of a self-signing service account key,
+// or with application default credentials the project of the metadata host, the default credentials
+// or the GCE metadata server. Empty means the client library detects it.
+func ProjectID(ctx context.Context, config ClientConfig) (string, error) {
+	if config.ProjectID != "" {
+		return config.ProjectID, nil
+	}
+	if config.SelfSigned && !config.ADC {
+		// the key is passed as a token source, which hides its project from client libraries
+		var key struct {
+			ProjectID string `json:"project_id"`
+		}
+		_ = json.Unmarshal([]byte(config.Credentials), &key)
+		return key.ProjectID, nil
+	}
+	if !config.ADC {
+		return "", nil
+	}
+	if config.MetadataHost != "" {
+		data, err := metadataGet(ctx, config.MetadataHost, "project/project-id")
+		if err != nil {
+			return "", fmt.Errorf("unable to get project ID from metadata server: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	// token sources passed to client libraries carry no project, so look it up like they would
+	if creds, err := google.FindDefaultCredentials(ctx, config.Scopes...); err == nil && creds.ProjectID != "" {
+		return creds.ProjectID, nil
+	}
+	if metadata.OnGCEWithContext(ctx) {
+		projectID, err := metadata.ProjectIDWithContext(ctx)
+		if err != nil {
+			return "", fmt.Errorf("unable to get project ID from metadata server: %v", err)
+		}
+		return projectID, nil
+	}
+	return "", nil
+}
+
+// metadataTokenSource fetches access tokens of the default service account
+// of a metadata server, e.g. the GKE Workload Identity one
+type metadataTokenSource struct {
+	ctx    context.Context
+	host   string
+	scopes []string
+}
+
+// Token implements oauth2.TokenSource
+func (s *metadataTokenSource) Token() (*oauth2.Token, error) {
+	path := "instance/service-accounts/default/token"
+	if len(s.scopes) > 0 {
+		path += "?scopes=" + url.QueryEscape(strings.Join(s.scopes, ","))
+	}
+	data, err := metadataGet(s.ctx, s.host, path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token from metadata server: %v", err)
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid metadata server token: %v", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("metadata server returned an empty token")
+	}
+	return &oauth2.Token{
+		AccessToken: resp.AccessToken,
+		TokenType:   resp.TokenType,
+		Expiry:      time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// metadataGet reads a metadata server value, host is like GCE_METADATA_HOST, e.g. 169.254.169.254
+func metadataGet(ctx context.Context, host, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", metadataFlavor)
+
+	// the client of ctx set by NewGoogleHTTPClient, so cassettes cover metadata calls too
+	resp, err := oauth2.NewClient(ctx, nil).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,86 @@
+package etc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMetadataServer serves a token and the project of a metadata server stand-in
+func newMetadataServer(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != metadataFlavor {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.URL.Query().Get("scopes") != "a,b" {
+				http.Error(w, "unexpected scopes "+r.URL.Query().Get("scopes"), http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"metadata-token","expires_in":3600,"token_type":"Bearer"}`))
+		case "/computeMetadata/v1/project/project-id":
+			_, _ = w.Write([]byte("metadata-project\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestADCMetadataHost(t *testing.T) {
+	host := newMetadataServer(t)
+	config := ClientConfig{ADC: true, MetadataHost: host, Scopes: []string{"a", "b"}}
+	ctx := context.Background()
+
+	ts, err := ADCTokenSource(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "metadata-token" || !token.Valid() {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	project, err := ProjectID(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project != "metadata-project" {
+		t.Fatalf("project %q, want metadata-project", project)
+	}
+
+	if got := Principal(config, nil); got != "adc:"+host {
+		t.Fatalf("principal %q", got)
+	}
+}
+
+func TestADCMetadataError(t *testing.T) {
+	host := newMetadataServer(t)
+	ts, err := ADCTokenSource(context.Background(), ClientConfig{ADC: true, MetadataHost: host, Scopes: []string{"other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("expected an error for a failed metadata request")
+	}
+}
+
+func TestAuthorizedUserPrincipal(t *testing.T) {
+	config := ClientConfig{Credentials: `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"refresh"}`}
+	got := Principal(config, &Token{AccessToken: "access"})
+	if got != TokenPrincipal("", "refresh") {
+		t.Fatalf("principal %q isn't based on the refresh token of the credentials", got)
+	}
+	if strings.Contains(got, "refresh") {
+		t.Fatalf("principal %q contains the refresh token", got)
+	}
+}
@@ -0,0 +1,37 @@
+package etc
+
+import "net/http"
+
+const (
+	// APIKeyHeader is the header carrying API keys
+	APIKeyHeader = "X-Goog-Api-Key"
+	// APIKeyParam is the query parameter carrying API keys
+	APIKeyParam = "key"
+)
+
+// APIKeyTransport authorizes requests with an API key, sent in the X-Goog-Api-Key
+// header by default or in the key query parameter
+type APIKeyTransport struct {
+	Key     string
+	InQuery bool
+	Base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *APIKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// requests must not be modified by transports
+	req = req.Clone(req.Context())
+	if t.InQuery {
+		query := req.URL.Query()
+		query.Set(APIKeyParam, t.Key)
+		req.URL.RawQuery = query.Encode()
+	} else {
+		req.Header.Set(APIKeyHeader, t.Key)
+	}
+	return base.RoundTrip(req)
+}
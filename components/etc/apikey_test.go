@@ -0,0 +1,62 @@
+package etc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewGoogleHTTPClientAPIKey(t *testing.T) {
+	var header, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(APIKeyHeader)
+		query = r.URL.Query().Get(APIKeyParam)
+	}))
+	defer srv.Close()
+
+	client, err := NewGoogleHTTPClient(context.Background(), ClientConfig{APIKey: "public-key"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(srv.URL + "/calendars/primary/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if header != "public-key" || query != "" {
+		t.Fatalf("key sent as header %q and query %q, want header", header, query)
+	}
+}
+
+func TestNewGoogleHTTPClientWithoutCredentials(t *testing.T) {
+	_, err := NewGoogleHTTPClient(context.Background(), ClientConfig{Scopes: []string{"https://www.googleapis.com/auth/calendar"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Fatalf("expected missing credentials error, got %v", err)
+	}
+}
+
+func TestAPIKeyTransportInQuery(t *testing.T) {
+	var header, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(APIKeyHeader)
+		query = r.URL.Query().Get(APIKeyParam)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"?maxResults=10", nil)
+	resp, err := (&http.Client{Transport: &APIKeyTransport{Key: "public-key", InQuery: true}}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if header != "" || query != "public-key" {
+		t.Fatalf("key sent as header %q and query %q, want query", header, query)
+	}
+	if req.URL.RawQuery != "maxResults=10" {
+		t.Fatalf("request was modified: %s", req.URL.RawQuery)
+	}
+}
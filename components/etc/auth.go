@@ -6,30 +6,112 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
+	"github.com/tiny-systems/googleapis-module/pkg/clientpool"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
+// Types of credentials JSON, OAuth client credentials have none
+const (
+	ServiceAccount             = "service_account"
+	AuthorizedUser             = "authorized_user"
+	ExternalAccount            = "external_account"
+	ImpersonatedServiceAccount = "impersonated_service_account"
+)
+
 // credentialFile is used to detect the credential type from JSON.
 type credentialFile struct {
 	Type string `json:"type"`
 }
 
+// CredentialsType returns the type of the credentials JSON, empty for OAuth client credentials
+func CredentialsType(config ClientConfig) string {
+	var cf credentialFile
+	_ = json.Unmarshal([]byte(config.Credentials), &cf)
+	return cf.Type
+}
+
 // NewGoogleHTTPClient returns an authenticated *http.Client based on the credential type.
-// Service account JSON uses JWT with optional subject impersonation.
+// Service account JSON uses JWT with optional subject impersonation, or signs access tokens itself.
+// gcloud authorized_user JSON uses its refresh token.
+// external_account JSON exchanges a subject token for a Google token via STS (workload identity federation).
 // OAuth2 JSON uses the provided token.
+// Application default credentials come from the environment or the metadata server.
+// Any of these may mint tokens for another service account via IAM impersonation.
+// Without credentials requests are authorized with the API key.
 func NewGoogleHTTPClient(ctx context.Context, config ClientConfig, token *Token) (*http.Client, error) {
+	// Requests made by the returned client are traced and measured, and recorded or replayed in cassette mode
+	base := cassette.NewTransport(telemetry.NewTransport(http.DefaultTransport))
+
+	if !config.ADC && config.Credentials == "" {
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("no credentials: set credentials, an API key or enable application default credentials")
+		}
+		return &http.Client{Transport: &APIKeyTransport{Key: config.APIKey, Base: base}}, nil
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: base})
+	ts, err := TokenSource(ctx, config, token)
+	if err != nil {
+		return nil, err
+	}
+	// the current token is tracked so refreshed tokens can be emitted, see CurrentToken
+	return &http.Client{Transport: &oauth2.Transport{
+		Source: &trackingTokenSource{src: oauth2.ReuseTokenSource(nil, ts)},
+		Base:   base,
+	}}, nil
+}
+
+// httpClients shares authorized clients between messages, keeping their tokens cached
+var httpClients = clientpool.New[*http.Client](clientpool.DefaultIdleTimeout, nil)
+
+// SharedGoogleHTTPClient returns a NewGoogleHTTPClient client shared by calls with the same config, token
+// and cassette settings. The client must not be modified and must be released when the call is done.
+func SharedGoogleHTTPClient(ctx context.Context, config ClientConfig, token *Token) (*http.Client, func(), error) {
+	settings := cassette.SettingsFrom(ctx)
+	return httpClients.Acquire(ctx, clientpool.Key(config, token, settings), func(ctx context.Context) (*http.Client, error) {
+		// token requests are recorded or replayed like the calls made with the client
+		return NewGoogleHTTPClient(cassette.WithSettings(ctx, settings), config, token)
+	})
+}
+
+// TokenSource returns a token source of the config credentials, see NewGoogleHTTPClient.
+// Tokens are requested with the oauth2.HTTPClient of ctx if set.
+func TokenSource(ctx context.Context, config ClientConfig, token *Token) (oauth2.TokenSource, error) {
+	if Impersonated(config) {
+		return impersonatedTokenSource(ctx, config, token)
+	}
+	return baseTokenSource(ctx, config, token)
+}
+
+// baseTokenSource returns a token source of the credentials themselves
+func baseTokenSource(ctx context.Context, config ClientConfig, token *Token) (oauth2.TokenSource, error) {
+	if config.ADC {
+		return ADCTokenSource(ctx, config)
+	}
+
 	var cf credentialFile
 	if err := json.Unmarshal([]byte(config.Credentials), &cf); err != nil {
 		return nil, fmt.Errorf("unable to parse credentials JSON: %v", err)
 	}
-	if cf.Type == "service_account" {
-		return newServiceAccountClient(ctx, config)
+
+	switch cf.Type {
+	case ServiceAccount:
+		return serviceAccountTokenSource(ctx, config)
+	case AuthorizedUser:
+		return authorizedUserTokenSource(ctx, config)
+	case ExternalAccount:
+		return externalAccountTokenSource(ctx, config)
 	}
-	return newOAuth2Client(ctx, config, token)
+	return oauth2TokenSource(ctx, config, token)
 }
 
-func newServiceAccountClient(ctx context.Context, config ClientConfig) (*http.Client, error) {
+func serviceAccountTokenSource(ctx context.Context, config ClientConfig) (oauth2.TokenSource, error) {
+	if config.SelfSigned {
+		return selfSignedTokenSource(config)
+	}
 	jwtConfig, err := google.JWTConfigFromJSON([]byte(config.Credentials), config.Scopes...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse service account key: %v", err)
@@ -37,10 +119,44 @@ func newServiceAccountClient(ctx context.Context, config ClientConfig) (*http.Cl
 	if config.Subject != "" {
 		jwtConfig.Subject = config.Subject
 	}
-	return jwtConfig.Client(ctx), nil
+	return jwtConfig.TokenSource(ctx), nil
+}
+
+// selfSignedTokenSource signs JWT access tokens locally, skipping the token endpoint round-trip.
+// Tokens are reused until shortly before they expire.
+func selfSignedTokenSource(config ClientConfig) (oauth2.TokenSource, error) {
+	if config.Subject != "" {
+		// domain-wide delegation is granted by the token endpoint only
+		return nil, fmt.Errorf("self-signed JWTs do not support a subject")
+	}
+	var (
+		ts  oauth2.TokenSource
+		err error
+	)
+	if config.Audience != "" {
+		ts, err = google.JWTAccessTokenSourceFromJSON([]byte(config.Credentials), config.Audience)
+	} else {
+		scopes := config.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{cloudPlatformScope}
+		}
+		ts, err = google.JWTAccessTokenSourceWithScope([]byte(config.Credentials), scopes...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign JWT with service account key: %v", err)
+	}
+	return ts, nil
+}
+
+func authorizedUserTokenSource(ctx context.Context, config ClientConfig) (oauth2.TokenSource, error) {
+	creds, err := google.CredentialsFromJSONWithType(ctx, []byte(config.Credentials), google.AuthorizedUser, config.Scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse authorized user credentials: %v", err)
+	}
+	return creds.TokenSource, nil
 }
 
-func newOAuth2Client(ctx context.Context, config ClientConfig, token *Token) (*http.Client, error) {
+func oauth2TokenSource(ctx context.Context, config ClientConfig, token *Token) (oauth2.TokenSource, error) {
 	if token == nil {
 		return nil, fmt.Errorf("OAuth2 credentials require a token")
 	}
@@ -48,7 +164,7 @@ func newOAuth2Client(ctx context.Context, config ClientConfig, token *Token) (*h
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
-	return oauthConfig.Client(ctx, &oauth2.Token{
+	return oauthConfig.TokenSource(ctx, &oauth2.Token{
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
 		Expiry:       token.Expiry,
@@ -0,0 +1,107 @@
+package etc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
+)
+
+func TestSharedGoogleHTTPClient(t *testing.T) {
+	config := ClientConfig{APIKey: "public-key"}
+	acquire := func(ctx context.Context, config ClientConfig) any {
+		t.Helper()
+		client, release, err := SharedGoogleHTTPClient(ctx, config, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		return client
+	}
+
+	ctx := context.Background()
+	live := acquire(ctx, config)
+	if acquire(ctx, config) != live {
+		t.Fatal("calls with the same config don't share the client")
+	}
+	if acquire(ctx, ClientConfig{APIKey: "other-key"}) == live {
+		t.Fatal("calls with other credentials share the client")
+	}
+
+	recording := cassette.WithSettings(ctx, cassette.Settings{Mode: cassette.ModeRecord, Dir: t.TempDir()})
+	if acquire(recording, config) == live {
+		t.Fatal("recording and live calls share the client")
+	}
+	if acquire(cassette.WithSettings(ctx, cassette.Settings{}), config) != acquire(cassette.WithSettings(ctx, cassette.Settings{}), config) {
+		t.Fatal("calls with the same cassette settings don't share the client")
+	}
+}
+
+func TestSelfSignedTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	credentials, err := json.Marshal(map[string]string{
+		"type":           ServiceAccount,
+		"project_id":     "key-project",
+		"private_key_id": "key-id",
+		"private_key":    string(pemKey),
+		"client_email":   "sa@key-project.iam.gserviceaccount.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(config ClientConfig) map[string]any {
+		t.Helper()
+		ts, err := TokenSource(context.Background(), config, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.Split(token.AccessToken, ".")
+		if len(parts) != 3 {
+			t.Fatalf("access token %q is not a JWT", token.AccessToken)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var claims map[string]any
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+
+	config := ClientConfig{Credentials: string(credentials), SelfSigned: true}
+	if got := claims(config); got["scope"] != cloudPlatformScope || got["iss"] != "sa@key-project.iam.gserviceaccount.com" {
+		t.Fatalf("claims %v, want the default scope", got)
+	}
+	config.Audience = "https://firestore.googleapis.com/"
+	if got := claims(config); got["aud"] != config.Audience {
+		t.Fatalf("claims %v, want audience %s", got, config.Audience)
+	}
+
+	project, err := ProjectID(context.Background(), config)
+	if err != nil || project != "key-project" {
+		t.Fatalf("project %q, %v, want key-project", project, err)
+	}
+
+	config.Subject = "user@example.com"
+	if _, err := TokenSource(context.Background(), config, nil); err == nil {
+		t.Fatal("expected an error for a subject")
+	}
+}
@@ -1,7 +1,17 @@
 package etc
 
 type ClientConfig struct {
-	Credentials string   `json:"credentials" required:"true" format:"textarea" title:"Credentials" description:"Google client credentials.json or service account key file content"`
-	Scopes      []string `json:"scopes,omitempty" title:"Scopes"`
-	Subject     string   `json:"subject,omitempty" title:"Subject" description:"Email to impersonate via domain-wide delegation (service accounts only)"`
+	Credentials  string   `json:"credentials,omitempty" format:"textarea" title:"Credentials" description:"Google client credentials.json, service account key, gcloud authorized_user, impersonated_service_account or workload identity federation external_account file content. Leave empty to call public APIs with an API key"`
+	Scopes       []string `json:"scopes,omitempty" title:"Scopes"`
+	Subject      string   `json:"subject,omitempty" title:"Subject" description:"Email to impersonate via domain-wide delegation (service accounts only)"`
+	SelfSigned   bool     `json:"selfSignedJwt,omitempty" title:"Self-Signed JWT" description:"Sign access tokens locally with the service account key instead of exchanging a JWT at the token endpoint. Supported by most Google APIs, but not with a subject"`
+	Audience     string   `json:"audience,omitempty" title:"JWT Audience" description:"Audience of self-signed JWTs, e.g. https://firestore.googleapis.com/. Scopes are signed instead if empty"`
+	Impersonate  string   `json:"impersonate,omitempty" title:"Impersonate" description:"Service account to mint tokens for with the credentials above, which need roles/iam.serviceAccountTokenCreator on it"`
+	Delegates    []string `json:"delegates,omitempty" title:"Delegates" description:"Service accounts of the impersonation chain, each one needs roles/iam.serviceAccountTokenCreator on the next"`
+	Lifetime     int      `json:"lifetime,omitempty" title:"Token Lifetime" minimum:"0" maximum:"43200" description:"Seconds impersonated tokens are valid, defaults to an hour. Tokens are renewed automatically when they expire"`
+	APIKey       string   `json:"apiKey,omitempty" title:"API Key" description:"API key for public data, e.g. public calendars. Used when credentials are empty"`
+	ADC          bool     `json:"adc,omitempty" title:"Application Default Credentials" description:"Use credentials of the environment instead: GOOGLE_APPLICATION_CREDENTIALS, gcloud user credentials or the GCE/GKE metadata server (Workload Identity)"`
+	MetadataHost string   `json:"metadataHost,omitempty" title:"Metadata Host" description:"Metadata server used with application default credentials, e.g. localhost:8080 for a local stand-in. Defaults to GCE_METADATA_HOST or the GCE metadata server"`
+	ProjectID    string   `json:"projectId,omitempty" title:"Project ID" description:"Google Cloud project, detected from credentials or the metadata server if empty"`
+	STSEndpoint  string   `json:"stsEndpoint,omitempty" title:"STS Endpoint" description:"Token exchange endpoint of external_account credentials, overrides their token_url, e.g. for a local stand-in"`
 }
@@ -0,0 +1,38 @@
+package etc
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ExchangeCode exchanges an authorization code of the OAuth client config for a token.
+// redirectURL overrides the one of the config, verifier is the PKCE code verifier if any.
+// The OpenID Connect ID token of the response is returned too, empty without the openid scope.
+func ExchangeCode(ctx context.Context, config ClientConfig, code, redirectURL, verifier string) (*Token, string, error) {
+	oauthConfig, err := google.ConfigFromJSON([]byte(config.Credentials), config.Scopes...)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to parse client secret file to config: %v", err)
+	}
+
+	if redirectURL != "" {
+		oauthConfig.RedirectURL = redirectURL
+	}
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+	token, err := oauthConfig.Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	idToken, _ := token.Extra("id_token").(string)
+	return &Token{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+	}, idToken, nil
+}
@@ -0,0 +1,63 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google/externalaccount"
+)
+
+// externalAccountFile is external_account credentials JSON of workload identity federation,
+// the subject token comes from a file, a URL, an executable or AWS
+type externalAccountFile struct {
+	Audience                       string `json:"audience"`
+	SubjectTokenType               string `json:"subject_token_type"`
+	TokenURL                       string `json:"token_url"`
+	TokenInfoURL                   string `json:"token_info_url"`
+	ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	ServiceAccountImpersonation    struct {
+		TokenLifetimeSeconds int `json:"token_lifetime_seconds"`
+	} `json:"service_account_impersonation"`
+	ClientID                 string                           `json:"client_id"`
+	ClientSecret             string                           `json:"client_secret"`
+	QuotaProjectID           string                           `json:"quota_project_id"`
+	WorkforcePoolUserProject string                           `json:"workforce_pool_user_project"`
+	UniverseDomain           string                           `json:"universe_domain"`
+	CredentialSource         externalaccount.CredentialSource `json:"credential_source"`
+}
+
+// externalAccountTokenSource exchanges the subject token for a Google access token at the
+// STS endpoint of the config, or token_url of the credentials
+func externalAccountTokenSource(ctx context.Context, config ClientConfig) (oauth2.TokenSource, error) {
+	var f externalAccountFile
+	if err := json.Unmarshal([]byte(config.Credentials), &f); err != nil {
+		return nil, fmt.Errorf("unable to parse external account credentials: %v", err)
+	}
+
+	tokenURL := f.TokenURL
+	if config.STSEndpoint != "" {
+		tokenURL = config.STSEndpoint
+	}
+
+	ts, err := externalaccount.NewTokenSource(ctx, externalaccount.Config{
+		Audience:                       f.Audience,
+		SubjectTokenType:               f.SubjectTokenType,
+		TokenURL:                       tokenURL,
+		TokenInfoURL:                   f.TokenInfoURL,
+		ServiceAccountImpersonationURL: f.ServiceAccountImpersonationURL,
+		ServiceAccountImpersonationLifetimeSeconds: f.ServiceAccountImpersonation.TokenLifetimeSeconds,
+		ClientID:                 f.ClientID,
+		ClientSecret:             f.ClientSecret,
+		CredentialSource:         &f.CredentialSource,
+		QuotaProjectID:           f.QuotaProjectID,
+		Scopes:                   config.Scopes,
+		WorkforcePoolUserProject: f.WorkforcePoolUserProject,
+		UniverseDomain:           f.UniverseDomain,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create external account token source: %v", err)
+	}
+	return ts, nil
+}
@@ -0,0 +1,62 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalAccountTokenSource(t *testing.T) {
+	const audience = "//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/pool/providers/provider"
+
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Form.Get("subject_token") != "oidc-token" || r.Form.Get("audience") != audience {
+			http.Error(w, "unexpected exchange "+r.Form.Encode(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"federated-token","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer sts.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("oidc-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := json.Marshal(map[string]any{
+		"type":               ExternalAccount,
+		"audience":           audience,
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url":          "https://sts.googleapis.com/v1/token",
+		"credential_source":  map[string]any{"file": tokenFile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the STS endpoint of the config wins over token_url
+	config := ClientConfig{Credentials: string(credentials), STSEndpoint: sts.URL, Scopes: []string{cloudPlatformScope}}
+	ts, err := TokenSource(context.Background(), config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "federated-token" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	if got := Principal(config, nil); got != "external:"+audience {
+		t.Fatalf("principal %q", got)
+	}
+}
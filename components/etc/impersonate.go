@@ -0,0 +1,99 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// cloudPlatformScope lets base credentials call the IAM Credentials API
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// serviceAccountName prefixes service accounts in IAM resource names
+const serviceAccountName = "projects/-/serviceAccounts/"
+
+// impersonatedFile is impersonated_service_account credentials JSON, e.g. written by
+// gcloud auth application-default login --impersonate-service-account
+type impersonatedFile struct {
+	ServiceAccountImpersonationURL string          `json:"service_account_impersonation_url"`
+	Delegates                      []string        `json:"delegates"`
+	SourceCredentials              json.RawMessage `json:"source_credentials"`
+}
+
+// Impersonated reports whether tokens are minted for another service account
+func Impersonated(config ClientConfig) bool {
+	return config.Impersonate != "" || (!config.ADC && CredentialsType(config) == ImpersonatedServiceAccount)
+}
+
+// impersonatedTokenSource mints tokens of the target service account with the base credentials,
+// through the delegate chain if any. Subject is applied to the target for domain-wide delegation.
+func impersonatedTokenSource(ctx context.Context, config ClientConfig, token *Token) (oauth2.TokenSource, error) {
+	base := config
+	base.Impersonate, base.Delegates, base.Subject = "", nil, ""
+	base.Scopes = []string{cloudPlatformScope}
+
+	target, delegates := config.Impersonate, config.Delegates
+	if !config.ADC && CredentialsType(config) == ImpersonatedServiceAccount {
+		var f impersonatedFile
+		if err := json.Unmarshal([]byte(config.Credentials), &f); err != nil {
+			return nil, fmt.Errorf("unable to parse impersonated service account credentials: %v", err)
+		}
+		if len(f.SourceCredentials) == 0 {
+			return nil, fmt.Errorf("impersonated service account credentials have no source_credentials")
+		}
+		base.Credentials = string(f.SourceCredentials)
+		// explicit config wins over the file
+		if target == "" {
+			target = targetFromURL(f.ServiceAccountImpersonationURL)
+		}
+		if len(delegates) == 0 {
+			delegates = f.Delegates
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("service account to impersonate is not set")
+	}
+
+	baseTS, err := baseTokenSource(ctx, base, token)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{cloudPlatformScope}
+	}
+	names := make([]string, len(delegates))
+	for i, d := range delegates {
+		names[i] = strings.TrimPrefix(d, serviceAccountName)
+	}
+
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: target,
+		Scopes:          scopes,
+		Delegates:       names,
+		Lifetime:        time.Duration(config.Lifetime) * time.Second,
+		Subject:         config.Subject,
+	}, option.WithHTTPClient(oauth2.NewClient(ctx, baseTS)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to impersonate %s: %v", target, err)
+	}
+	return ts, nil
+}
+
+// targetFromURL extracts the service account of a generateAccessToken URL like
+// https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/sa@p.iam.gserviceaccount.com:generateAccessToken
+func targetFromURL(url string) string {
+	_, name, ok := strings.Cut(url, serviceAccountName)
+	if !ok {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
@@ -0,0 +1,121 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// roundTripFunc answers requests without network access
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func TestImpersonatedTokenSource(t *testing.T) {
+	var got struct {
+		Delegates []string `json:"delegates"`
+		Lifetime  string   `json:"lifetime"`
+		Scope     []string `json:"scope"`
+	}
+	iam := func(req *http.Request) *http.Response {
+		rec := httptest.NewRecorder()
+		switch {
+		case req.URL.Host == "metadata.test":
+			// the base credentials always get the cloud-platform scope
+			if req.URL.Query().Get("scopes") != cloudPlatformScope {
+				http.Error(rec, "unexpected scopes", http.StatusBadRequest)
+				break
+			}
+			_, _ = rec.WriteString(`{"access_token":"base-token","expires_in":3600}`)
+		case req.URL.String() == "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/target@p.iam.gserviceaccount.com:generateAccessToken":
+			if req.Header.Get("Authorization") != "Bearer base-token" {
+				http.Error(rec, "not authorized by the base credentials", http.StatusUnauthorized)
+				break
+			}
+			body, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(body, &got)
+			_, _ = rec.WriteString(`{"accessToken":"impersonated-token","expireTime":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`)
+		default:
+			http.NotFound(rec, req)
+		}
+		return rec.Result()
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: roundTripFunc(iam)})
+
+	config := ClientConfig{
+		ADC:          true,
+		MetadataHost: "metadata.test",
+		Impersonate:  "target@p.iam.gserviceaccount.com",
+		Delegates:    []string{"projects/-/serviceAccounts/first@p.iam.gserviceaccount.com", "second@p.iam.gserviceaccount.com"},
+		Lifetime:     7200,
+		Scopes:       []string{"https://www.googleapis.com/auth/calendar"},
+	}
+	ts, err := TokenSource(ctx, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "impersonated-token" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	wantDelegates := []string{"projects/-/serviceAccounts/first@p.iam.gserviceaccount.com", "projects/-/serviceAccounts/second@p.iam.gserviceaccount.com"}
+	if !reflect.DeepEqual(got.Delegates, wantDelegates) || got.Lifetime != "7200s" || !reflect.DeepEqual(got.Scope, config.Scopes) {
+		t.Fatalf("generateAccessToken request %+v", got)
+	}
+}
+
+func TestImpersonatedFile(t *testing.T) {
+	const file = `{
+		"type": "impersonated_service_account",
+		"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/target@p.iam.gserviceaccount.com:generateAccessToken",
+		"delegates": ["projects/-/serviceAccounts/first@p.iam.gserviceaccount.com"],
+		"source_credentials": {"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "refresh"}
+	}`
+
+	config := ClientConfig{Credentials: file, Subject: "user@example.com"}
+	if !Impersonated(config) {
+		t.Fatal("impersonated_service_account credentials aren't impersonated")
+	}
+	if got := Principal(config, nil); got != "target@p.iam.gserviceaccount.com/user@example.com" {
+		t.Fatalf("principal %q", got)
+	}
+	// explicit config wins over the file
+	config.Impersonate, config.Subject = "other@p.iam.gserviceaccount.com", ""
+	if got := Principal(config, nil); got != "other@p.iam.gserviceaccount.com" {
+		t.Fatalf("principal %q", got)
+	}
+
+	if Impersonated(ClientConfig{ADC: true, Credentials: file}) {
+		t.Fatal("ignored credentials of an ADC config are impersonated")
+	}
+	if _, err := TokenSource(context.Background(), ClientConfig{Credentials: `{"type":"impersonated_service_account","service_account_impersonation_url":"x"}`}, nil); err == nil || !strings.Contains(err.Error(), "source_credentials") {
+		t.Fatalf("expected a source_credentials error, got %v", err)
+	}
+}
+
+func TestTargetFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/sa@p.iam.gserviceaccount.com:generateAccessToken": "sa@p.iam.gserviceaccount.com",
+		"https://example.com/token": "",
+		"":                          "",
+	}
+	for url, want := range tests {
+		if got := targetFromURL(url); got != want {
+			t.Errorf("targetFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
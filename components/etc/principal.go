@@ -0,0 +1,84 @@
+package etc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Principal identifies on whose behalf Google API calls are made.
+// Service accounts are identified by their email and delegated subject,
+// OAuth2 users and API keys by a hash so secrets never leak into keys or logs.
+func Principal(config ClientConfig, token *Token) string {
+	if Impersonated(config) {
+		return impersonatedPrincipal(config)
+	}
+	if config.ADC {
+		// the environment has a single identity, unless a metadata stand-in is used
+		if config.MetadataHost != "" {
+			return "adc:" + config.MetadataHost
+		}
+		return "adc"
+	}
+	if config.Credentials == "" && config.APIKey != "" {
+		return APIKeyPrincipal(config.APIKey)
+	}
+	var sa struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		RefreshToken string `json:"refresh_token"`
+		Audience     string `json:"audience"`
+	}
+	if err := json.Unmarshal([]byte(config.Credentials), &sa); err == nil && sa.ClientEmail != "" {
+		if config.Subject != "" {
+			return sa.ClientEmail + "/" + config.Subject
+		}
+		return sa.ClientEmail
+	}
+	if sa.Type == ExternalAccount {
+		// the workload identity pool provider
+		return "external:" + sa.Audience
+	}
+	if sa.RefreshToken != "" {
+		// gcloud authorized_user credentials
+		return TokenPrincipal("", sa.RefreshToken)
+	}
+	if token == nil {
+		return ""
+	}
+	return TokenPrincipal(token.AccessToken, token.RefreshToken)
+}
+
+// TokenPrincipal hashes an OAuth2 token into a stable principal identifier.
+// The refresh token is preferred since it outlives rotating access tokens.
+func TokenPrincipal(accessToken, refreshToken string) string {
+	secret := refreshToken
+	if secret == "" {
+		secret = accessToken
+	}
+	return "token:" + fingerprint(secret)
+}
+
+// APIKeyPrincipal hashes an API key into a stable principal identifier
+func APIKeyPrincipal(key string) string {
+	return "key:" + fingerprint(key)
+}
+
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// impersonatedPrincipal identifies the impersonated service account and delegated subject
+func impersonatedPrincipal(config ClientConfig) string {
+	target := config.Impersonate
+	if target == "" {
+		var f impersonatedFile
+		_ = json.Unmarshal([]byte(config.Credentials), &f)
+		target = targetFromURL(f.ServiceAccountImpersonationURL)
+	}
+	if config.Subject != "" {
+		return target + "/" + config.Subject
+	}
+	return target
+}
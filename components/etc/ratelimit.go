@@ -0,0 +1,58 @@
+package etc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+)
+
+// RateLimit waits until a call of the service fits into the rate limit, see ratelimit.Wait.
+// Limits per principal are kept per Principal of the config and token, limits per project per QuotaProject.
+func RateLimit(ctx context.Context, service string, config ClientConfig, token *Token, settings ratelimit.Settings) error {
+	if !settings.Enabled {
+		return nil
+	}
+	key := Principal(config, token)
+	if settings.Scope == ratelimit.ScopeProject {
+		project, err := QuotaProject(ctx, config)
+		if err != nil {
+			return err
+		}
+		key = project
+	}
+	return ratelimit.Wait(ctx, service, key, settings)
+}
+
+// QuotaProject returns the Google Cloud project whose quota calls with the config use: the ProjectID,
+// the quota project of gcloud user credentials or the project of a service account key or OAuth client
+func QuotaProject(ctx context.Context, config ClientConfig) (string, error) {
+	project, err := ProjectID(ctx, config)
+	if err != nil || project != "" {
+		return project, err
+	}
+
+	type oauthClient struct {
+		ProjectID string `json:"project_id"`
+	}
+	var file struct {
+		ProjectID      string       `json:"project_id"`
+		QuotaProjectID string       `json:"quota_project_id"`
+		Web            *oauthClient `json:"web"`
+		Installed      *oauthClient `json:"installed"`
+	}
+	_ = json.Unmarshal([]byte(config.Credentials), &file)
+
+	switch {
+	case file.QuotaProjectID != "":
+		return file.QuotaProjectID, nil
+	case file.ProjectID != "":
+		return file.ProjectID, nil
+	case file.Web != nil && file.Web.ProjectID != "":
+		return file.Web.ProjectID, nil
+	case file.Installed != nil && file.Installed.ProjectID != "":
+		return file.Installed.ProjectID, nil
+	}
+	return "", fmt.Errorf("unable to determine the project of the credentials, set the project ID of the client config")
+}
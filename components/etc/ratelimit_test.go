@@ -0,0 +1,41 @@
+package etc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQuotaProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientConfig
+		want   string
+		err    bool
+	}{
+		{name: "configured", config: ClientConfig{ProjectID: "configured", Credentials: `{"type":"service_account","project_id":"key"}`}, want: "configured"},
+		{name: "service account key", config: ClientConfig{Credentials: `{"type":"service_account","project_id":"key"}`}, want: "key"},
+		{name: "gcloud user", config: ClientConfig{Credentials: `{"type":"authorized_user","quota_project_id":"quota"}`}, want: "quota"},
+		{name: "web client", config: ClientConfig{Credentials: `{"web":{"client_id":"id","project_id":"web"}}`}, want: "web"},
+		{name: "installed client", config: ClientConfig{Credentials: `{"installed":{"client_id":"id","project_id":"installed"}}`}, want: "installed"},
+		{name: "api key", config: ClientConfig{APIKey: "public-key"}, err: true},
+		{name: "gcloud user without quota project", config: ClientConfig{Credentials: `{"type":"authorized_user"}`}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QuotaProject(context.Background(), tt.config)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package etc
+
+// Discovery IDs of services used by typed components. Rate limits are keyed by
+// these IDs, so typed components share budgets with google_api_call.
+const (
+	CalendarService  = "calendar:v3"
+	FirestoreService = "firestore:v1"
+)
@@ -0,0 +1,196 @@
+package etc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultStateTTL is how long a sign-in may take between the auth URL and the code exchange
+const DefaultStateTTL = 10 * time.Minute
+
+// ErrInvalidState is returned for a state that was not signed by us, was altered or has expired
+var ErrInvalidState = errors.New("invalid OAuth state")
+
+// stateEnvelope is the signed content of an OAuth state parameter
+type stateEnvelope struct {
+	Value    string `json:"v,omitempty"`
+	Verifier string `json:"k,omitempty"`
+	Session  string `json:"s,omitempty"`
+	Nonce    string `json:"n"`
+	Expires  int64  `json:"e"`
+}
+
+// StateKey returns the key signing OAuth states. Without a configured secret the key is
+// derived from the client secret, so the auth URL and exchange components share it without setup.
+func StateKey(config ClientConfig, secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	var file struct {
+		Web *struct {
+			ClientSecret string `json:"client_secret"`
+		} `json:"web"`
+		Installed *struct {
+			ClientSecret string `json:"client_secret"`
+		} `json:"installed"`
+	}
+	_ = json.Unmarshal([]byte(config.Credentials), &file)
+
+	var clientSecret string
+	switch {
+	case file.Web != nil:
+		clientSecret = file.Web.ClientSecret
+	case file.Installed != nil:
+		clientSecret = file.Installed.ClientSecret
+	}
+	if clientSecret == "" {
+		return nil, fmt.Errorf("state secret is required for credentials without a client secret")
+	}
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte("oauth state"))
+	return mac.Sum(nil), nil
+}
+
+// State is the content of a signed OAuth state parameter
+type State struct {
+	// Value is carried through the sign-in for the caller, it is readable by the user
+	Value string
+	// Verifier is a PKCE code verifier, it is encrypted
+	Verifier string
+	// Session identifies the browser session starting the sign-in, only a hash of it is carried.
+	// States bound to a session only pass verification in the same session.
+	Session string
+}
+
+// SignState returns a state parameter carrying the state, a random nonce and an expiry, signed with key
+func SignState(key []byte, state State, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = DefaultStateTTL
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate state: %v", err)
+	}
+	env := stateEnvelope{
+		Value:   state.Value,
+		Nonce:   base64.RawURLEncoding.EncodeToString(nonce),
+		Expires: time.Now().Add(ttl).Unix(),
+	}
+	if state.Session != "" {
+		env.Session = sessionBinding(key, state.Session)
+	}
+	if state.Verifier != "" {
+		sealed, err := sealVerifier(key, state.Verifier)
+		if err != nil {
+			return "", err
+		}
+		env.Verifier = sealed
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + stateSignature(key, encoded), nil
+}
+
+// VerifyState checks the signature and expiry of a state made by SignState. States bound to a session
+// must arrive in the same session, so states obtained by someone else are rejected.
+func VerifyState(key []byte, state, session string) (State, error) {
+	encoded, signature, ok := strings.Cut(state, ".")
+	if !ok {
+		return State{}, ErrInvalidState
+	}
+	if !hmac.Equal([]byte(signature), []byte(stateSignature(key, encoded))) {
+		return State{}, ErrInvalidState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return State{}, ErrInvalidState
+	}
+	var env stateEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return State{}, ErrInvalidState
+	}
+	if time.Now().Unix() > env.Expires {
+		return State{}, fmt.Errorf("%w: expired", ErrInvalidState)
+	}
+	var result State
+	if env.Session != "" {
+		if session == "" {
+			return State{}, fmt.Errorf("%w: issued to a session, but no session was given", ErrInvalidState)
+		}
+		if !hmac.Equal([]byte(env.Session), []byte(sessionBinding(key, session))) {
+			return State{}, fmt.Errorf("%w: issued to another session", ErrInvalidState)
+		}
+		result.Session = session
+	}
+	result.Value = env.Value
+	if env.Verifier != "" {
+		if result.Verifier, err = openVerifier(key, env.Verifier); err != nil {
+			return State{}, ErrInvalidState
+		}
+	}
+	return result, nil
+}
+
+// verifierCipher encrypts PKCE verifiers with a key derived from the state key
+func verifierCipher(key []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("pkce verifier"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealVerifier(key []byte, verifier string) (string, error) {
+	aead, err := verifierCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to encrypt code verifier: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(verifier), nil)), nil
+}
+
+func openVerifier(key []byte, sealed string) (string, error) {
+	aead, err := verifierCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", ErrInvalidState
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// sessionBinding hashes the session, so the readable state doesn't disclose it
+func sessionBinding(key []byte, session string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("session\x00" + session))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func stateSignature(key []byte, encoded string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,96 @@
+package etc
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyState(t *testing.T) {
+	key := []byte("state key")
+	sign := func(state State, ttl time.Duration) string {
+		s, err := SignState(key, state, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	signed := sign(State{Value: "/return", Verifier: "verifier", Session: "session"}, time.Minute)
+	encoded, signature, _ := strings.Cut(signed, ".")
+
+	// flips a byte of the payload, keeping the signature
+	tampered := func() string {
+		payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+		payload[len(payload)/2] ^= 1
+		return base64.RawURLEncoding.EncodeToString(payload) + "." + signature
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		state   string
+		session string
+		want    State
+		err     bool
+	}{
+		{
+			name:    "valid",
+			key:     key,
+			state:   signed,
+			session: "session",
+			want:    State{Value: "/return", Verifier: "verifier", Session: "session"},
+		},
+		{name: "other session", key: key, state: signed, session: "attacker", err: true},
+		{name: "no session", key: key, state: signed, err: true},
+		{name: "state without session", key: key, state: sign(State{Value: "/return"}, time.Minute), want: State{Value: "/return"}},
+		{name: "state without session in a session", key: key, state: sign(State{Value: "/return"}, time.Minute), session: "session", want: State{Value: "/return"}},
+		{name: "state without session, other key", key: []byte("other key"), state: sign(State{Value: "/return"}, time.Minute), err: true},
+		{name: "other key", key: []byte("other key"), state: signed, session: "session", err: true},
+		{name: "tampered payload", key: key, state: tampered(), session: "session", err: true},
+		{name: "tampered signature", key: key, state: encoded + "." + signature[1:], session: "session", err: true},
+		{name: "no signature", key: key, state: encoded, session: "session", err: true},
+		{name: "empty", key: key, session: "session", err: true},
+		{name: "default ttl", key: key, state: sign(State{Session: "session"}, 0), session: "session", want: State{Session: "session"}},
+		{name: "expired", key: key, state: expiredState(t, key), session: "session", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyState(tt.key, tt.state, tt.session)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidState) {
+					t.Fatalf("expected ErrInvalidState, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// expiredState signs a state whose expiry has passed
+func expiredState(t *testing.T, key []byte) string {
+	t.Helper()
+	payload := `{"s":"` + sessionBinding(key, "session") + `","n":"nonce","e":1}`
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + stateSignature(key, encoded)
+}
+
+func TestVerifyStateHidesVerifierAndSession(t *testing.T) {
+	signed, err := SignState([]byte("state key"), State{Verifier: "secret-verifier", Session: "secret-session"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _, _ := strings.Cut(signed, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+	if strings.Contains(string(payload), "secret") {
+		t.Fatalf("state discloses its secrets: %s", payload)
+	}
+}
@@ -1,6 +1,12 @@
 package etc
 
-import "time"
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
 
 type Token struct {
 	AccessToken  string    `json:"access_token" required:"true" minLength:"1" title:"AccessToken" description:"Token that authorizes and authenticates"`
@@ -8,3 +14,57 @@ type Token struct {
 	RefreshToken string    `json:"refresh_token,omitempty" title:"RefreshToken" description:"Token that's used by the application (as opposed to the user) to refresh the access token if it expires."`
 	Expiry       time.Time `json:"expiry,omitempty" title:"Expiry" description:"Expiry is the optional expiration time of the access token. If zero, TokenSource implementations will reuse the same token forever and RefreshToken or equivalent mechanisms for that TokenSource will not be used. 2012-10-01T09:45:00.000+02:00"`
 }
+
+// trackingTokenSource remembers the last token handed out, so tokens refreshed by a client can be persisted
+type trackingTokenSource struct {
+	src oauth2.TokenSource
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (t *trackingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := t.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.last = tok
+	t.mu.Unlock()
+	return tok, nil
+}
+
+// CurrentToken returns the token a NewGoogleHTTPClient client authorizes requests with,
+// and whether it was refreshed and differs from token. Without a token nothing is returned,
+// since only OAuth2 user tokens are persisted by flows.
+func CurrentToken(client *http.Client, token *Token) (*Token, bool) {
+	if token == nil || client == nil {
+		return nil, false
+	}
+	transport, ok := client.Transport.(*oauth2.Transport)
+	if !ok {
+		return token, false
+	}
+	tracking, ok := transport.Source.(*trackingTokenSource)
+	if !ok {
+		return token, false
+	}
+	tracking.mu.Lock()
+	last := tracking.last
+	tracking.mu.Unlock()
+	if last == nil || last.AccessToken == token.AccessToken {
+		return token, false
+	}
+
+	current := &Token{
+		AccessToken:  last.AccessToken,
+		TokenType:    last.Type(),
+		RefreshToken: last.RefreshToken,
+		Expiry:       last.Expiry,
+	}
+	// refresh responses usually omit the refresh token
+	if current.RefreshToken == "" {
+		current.RefreshToken = token.RefreshToken
+	}
+	return current, true
+}
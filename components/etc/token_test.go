@@ -0,0 +1,58 @@
+package etc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCurrentToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"renewed","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	config := ClientConfig{Credentials: `{"web":{"client_id":"id","client_secret":"secret","auth_uri":"` + server.URL + `/auth","token_uri":"` + server.URL + `/token","redirect_uris":["http://localhost/callback"]}}`}
+	call := func(token *Token) *http.Client {
+		t.Helper()
+		client, err := NewGoogleHTTPClient(context.Background(), config, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL + "/api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return client
+	}
+
+	valid := &Token{AccessToken: "current", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	if current, refreshed := CurrentToken(call(valid), valid); refreshed || current != valid {
+		t.Fatalf("valid token reported as refreshed %+v", current)
+	}
+
+	expired := &Token{AccessToken: "current", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(-time.Hour)}
+	current, refreshed := CurrentToken(call(expired), expired)
+	if !refreshed || current.AccessToken != "renewed" || !current.Expiry.After(time.Now()) {
+		t.Fatalf("refreshed token %+v, %v", current, refreshed)
+	}
+	// the refresh response omits the refresh token
+	if current.RefreshToken != "refresh" {
+		t.Fatalf("refresh token %q is lost", current.RefreshToken)
+	}
+
+	if current, refreshed := CurrentToken(&http.Client{}, nil); current != nil || refreshed {
+		t.Fatalf("token %+v without a token", current)
+	}
+	if current, refreshed := CurrentToken(&http.Client{}, valid); current != valid || refreshed {
+		t.Fatalf("token %+v of a client without token source", current)
+	}
+}
@@ -2,13 +2,13 @@ package create
 
 import (
 	"context"
-	firebase "firebase.google.com/go"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/components/firestore/utils"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
-	"google.golang.org/api/option"
 )
 
 const (
@@ -21,7 +21,8 @@ const (
 type Context any
 
 type Settings struct {
-	EnableErrorPort bool `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	EnableErrorPort bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	RateLimit       ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
 }
 
 type Component struct {
@@ -72,8 +73,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		return module.Fail(fmt.Errorf("invalid request"))
 	}
 
-	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsJSON([]byte(req.Config.Credentials)), option.WithScopes(req.Config.Scopes...))
-	if err != nil {
+	if err := etc.RateLimit(ctx, etc.FirestoreService, req.Config, nil, g.settings.RateLimit); err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
 			return module.Fail(err)
@@ -84,17 +84,18 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		})
 	}
 
-	db, err := app.Firestore(ctx)
-
+	db, release, err := utils.Client(ctx, req.Config)
 	if err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
 			return module.Fail(err)
 		}
 		return output(ctx, ErrorPort, Error{
-			Error: err.Error(),
+			Context: req.Context,
+			Error:   err.Error(),
 		})
 	}
+	defer release()
 
 	ref := db.Collection(req.Collection)
 
@@ -3,15 +3,14 @@ package create
 import (
 	"context"
 	"errors"
-	firebase "firebase.google.com/go"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
 	"github.com/tiny-systems/googleapis-module/components/firestore/utils"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 )
 
 const (
@@ -26,8 +25,9 @@ type Context any
 type Document map[string]interface{}
 
 type Settings struct {
-	EnableErrorPort bool     `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
-	Document        Document `json:"document" configurable:"true" title:"Document Example" description:"Define document schema. Optional."`
+	EnableErrorPort bool               `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"`
+	Document        Document           `json:"document" configurable:"true" title:"Document Example" description:"Define document schema. Optional."`
+	RateLimit       ratelimit.Settings `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"`
 }
 
 type Component struct {
@@ -87,8 +87,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		return module.Fail(fmt.Errorf("invalid request"))
 	}
 
-	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsJSON([]byte(req.Config.Credentials)), option.WithScopes(req.Config.Scopes...))
-	if err != nil {
+	if err := etc.RateLimit(ctx, etc.FirestoreService, req.Config, nil, g.settings.RateLimit); err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
 			return module.Fail(err)
@@ -99,8 +98,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 		})
 	}
 
-	db, err := app.Firestore(ctx)
-
+	db, release, err := utils.Client(ctx, req.Config)
 	if err != nil {
 		// check err port
 		if !g.settings.EnableErrorPort {
@@ -111,6 +109,7 @@ func (g *Component) Handle(ctx context.Context, output module.Handler, port stri
 			Error:   err.Error(),
 		})
 	}
+	defer release()
 
 	ref := db.Collection(req.Collection)
 	q := ref.Query
@@ -4,15 +4,14 @@ import (
 	"cloud.google.com/go/firestore"
 	"context"
 	"errors"
-	firebase "firebase.google.com/go"
 	"fmt"
 	"github.com/tiny-systems/googleapis-module/components/etc"
 	"github.com/tiny-systems/googleapis-module/components/firestore/utils"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
 	"github.com/tiny-systems/module/api/v1alpha1"
 	"github.com/tiny-systems/module/module"
 	"github.com/tiny-systems/module/registry"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"sync"
@@ -134,9 +133,9 @@ func (g *Component) start(ctx context.Context, handler module.Handler) error {
 		_ = handler(context.Background(), v1alpha1.ReconcilePort, nil)
 	}()
 
-	app, err := firebase.NewApp(listenCtx, nil,
-		option.WithCredentialsJSON([]byte(g.startSettings.Config.Credentials)),
-		option.WithScopes(g.startSettings.Config.Scopes...),
+	app, err := utils.NewApp(listenCtx, g.startSettings.Config,
+		telemetry.GRPCClientOption(etc.FirestoreService),
+		telemetry.GRPCStreamClientOption(etc.FirestoreService),
 	)
 	if err != nil {
 		// check err port
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+
+	firebase "firebase.google.com/go"
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"google.golang.org/api/option"
+)
+
+// NewApp creates a Firebase app authorized by the client config, either with
+// inline credentials, external account credentials or application default credentials,
+// optionally impersonating another service account
+func NewApp(ctx context.Context, config etc.ClientConfig, opts ...option.ClientOption) (*firebase.App, error) {
+	if config.ADC || config.SelfSigned || etc.Impersonated(config) || etc.CredentialsType(config) == etc.ExternalAccount {
+		// token sources honour the metadata host, STS endpoint, self-signing and impersonation of the config
+		ts, err := etc.TokenSource(ctx, config, nil)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, option.WithTokenSource(ts))
+	} else {
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.Credentials)), option.WithScopes(config.Scopes...))
+	}
+
+	projectID, err := etc.ProjectID(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	// nil config keeps FIREBASE_CONFIG and credentials based project detection
+	var fbConfig *firebase.Config
+	if projectID != "" {
+		fbConfig = &firebase.Config{ProjectID: projectID}
+	}
+	return firebase.NewApp(ctx, fbConfig, opts...)
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/clientpool"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
+)
+
+// clients shares Firestore clients and their gRPC connections between messages
+var clients = clientpool.New[*firestore.Client](clientpool.DefaultIdleTimeout, func(db *firestore.Client) error {
+	return db.Close()
+})
+
+// Client returns a Firestore client shared by calls with the same config.
+// The client must be released when the call is done, it is closed once idle.
+func Client(ctx context.Context, config etc.ClientConfig) (*firestore.Client, func(), error) {
+	return clients.Acquire(ctx, clientpool.Key(config), func(ctx context.Context) (*firestore.Client, error) {
+		app, err := NewApp(ctx, config, telemetry.GRPCClientOption(etc.FirestoreService))
+		if err != nil {
+			return nil, err
+		}
+		return app.Firestore(ctx)
+	})
+}
@@ -0,0 +1,9 @@
+package googleapismodule
+
+import _ "embed"
+
+// DiscoveryList is a snapshot of the Google API discovery directory,
+// used when the directory can't be fetched or read from cache
+//
+//go:embed apis/discovery/list.json
+var DiscoveryList []byte
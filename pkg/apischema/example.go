@@ -0,0 +1,216 @@
+package apischema
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// exampleDepth limits how deep nested objects and arrays are populated
+const exampleDepth = 3
+
+// Fixed example values keep generated samples stable between schema builds
+const (
+	exampleDateTime = "2024-01-15T09:30:00Z"
+	exampleDate     = "2024-01-15"
+	exampleDuration = "3.5s"
+	exampleInt64    = "1234567890"
+	exampleBytes    = "ZXhhbXBsZQ=="
+	exampleString   = "example"
+)
+
+// patternPlaceholder matches a single resource name segment in discovery patterns
+var patternPlaceholder = regexp.MustCompile(`\[\^/\][+*]|\.[+*]`)
+
+// ExampleGenerator builds example values from discovery parameters and schemas
+type ExampleGenerator struct {
+	api      *googleapismodule.API
+	maxDepth int
+	visiting map[string]bool // refs on the current path, prevents infinite recursion
+
+	// SkipReadOnly omits output only fields, used for request samples
+	SkipReadOnly bool
+}
+
+// NewExampleGenerator creates a new example generator for an API
+func NewExampleGenerator(api *googleapismodule.API) *ExampleGenerator {
+	return &ExampleGenerator{
+		api:      api,
+		maxDepth: exampleDepth,
+		visiting: make(map[string]bool),
+	}
+}
+
+// Parameter returns an example value for a path or query parameter
+func (g *ExampleGenerator) Parameter(param googleapismodule.Parameter) any {
+	if param.Repeated || param.Type == "array" {
+		item := param
+		item.Repeated = false
+		if param.Items != nil {
+			item = *param.Items
+		}
+		if item.Type == "array" {
+			item.Type = "string"
+		}
+		return []any{g.Parameter(item)}
+	}
+	if param.Default != "" {
+		return convertDefault(param.Type, param.Default)
+	}
+	return g.scalar(param.Type, param.Format, param.Enum, param.Pattern, param.Minimum, param.Maximum)
+}
+
+// Schema returns an example value for a discovery schema
+func (g *ExampleGenerator) Schema(schema googleapismodule.Schema) any {
+	return g.schema(schema, 0)
+}
+
+func (g *ExampleGenerator) schema(schema googleapismodule.Schema, depth int) any {
+	if schema.Ref != "" {
+		if g.visiting[schema.Ref] {
+			return nil
+		}
+		resolved, ok := g.api.Schemas[schema.Ref]
+		if !ok {
+			return nil
+		}
+		g.visiting[schema.Ref] = true
+		defer delete(g.visiting, schema.Ref)
+		return g.schema(resolved, depth)
+	}
+
+	if schema.Default != nil {
+		if s, ok := schema.Default.(string); ok {
+			return convertDefault(schema.Type, s)
+		}
+		return schema.Default
+	}
+
+	switch schema.Type {
+	case "object":
+		if depth >= g.maxDepth {
+			return nil
+		}
+		obj := make(map[string]any)
+		for name, prop := range schema.Properties {
+			if g.SkipReadOnly && g.readOnly(prop) {
+				continue
+			}
+			if value := g.schema(prop, depth+1); value != nil {
+				obj[name] = value
+			}
+		}
+		if schema.AdditionalProperties != nil && len(schema.Properties) == 0 {
+			if value := g.schema(*schema.AdditionalProperties, depth+1); value != nil {
+				obj["key"] = value
+			}
+		}
+		return obj
+	case "array":
+		if depth >= g.maxDepth || schema.Items == nil {
+			return []any{}
+		}
+		if value := g.schema(*schema.Items, depth+1); value != nil {
+			return []any{value}
+		}
+		return []any{}
+	case "any":
+		return nil
+	}
+
+	return g.scalar(schema.Type, schema.Format, schema.Enum, schema.Pattern, schema.Minimum, schema.Maximum)
+}
+
+// readOnly reports whether a property (or the schema it references) is output only
+func (g *ExampleGenerator) readOnly(schema googleapismodule.Schema) bool {
+	if schema.ReadOnly {
+		return true
+	}
+	if schema.Ref == "" {
+		return false
+	}
+	return g.api.Schemas[schema.Ref].ReadOnly
+}
+
+func (g *ExampleGenerator) scalar(typ, format string, enum []string, pattern, minimum, maximum string) any {
+	if len(enum) > 0 {
+		return enum[0]
+	}
+
+	switch typ {
+	case "integer":
+		return int64(exampleNumber(1, minimum, maximum))
+	case "number":
+		return exampleNumber(1.5, minimum, maximum)
+	case "boolean":
+		return false
+	}
+
+	switch format {
+	case "date-time", "google-datetime":
+		return exampleDateTime
+	case "date":
+		return exampleDate
+	case "google-duration":
+		return exampleDuration
+	case "google-fieldmask":
+		return "*"
+	case "int64", "uint64", "int32", "uint32":
+		return exampleInt64
+	case "byte":
+		return exampleBytes
+	}
+
+	if pattern != "" {
+		if s, ok := exampleFromPattern(pattern); ok {
+			return s
+		}
+	}
+	return exampleString
+}
+
+// exampleNumber picks a value within discovery minimum/maximum bounds
+func exampleNumber(value float64, minimum, maximum string) float64 {
+	if lo, err := strconv.ParseFloat(minimum, 64); err == nil && value < lo {
+		value = lo
+	}
+	if hi, err := strconv.ParseFloat(maximum, 64); err == nil && value > hi {
+		value = hi
+	}
+	return value
+}
+
+// exampleFromPattern turns simple resource name patterns like ^projects/[^/]+$
+// into a matching value like projects/example
+func exampleFromPattern(pattern string) (string, bool) {
+	s := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	s = patternPlaceholder.ReplaceAllString(s, exampleString)
+	if strings.ContainsAny(s, `[](){}*+?|\`) {
+		return "", false
+	}
+	if re := compilePattern(pattern); re != nil && !re.MatchString(s) {
+		return "", false
+	}
+	return s, true
+}
+
+// convertDefault converts a string default of a parameter into its typed value
+func convertDefault(typ, value string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
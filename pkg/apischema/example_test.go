@@ -0,0 +1,78 @@
+package apischema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+const exampleAPI = `{
+	"schemas": {
+		"Event": {"id": "Event", "type": "object", "properties": {
+			"id": {"type": "string", "readOnly": true},
+			"summary": {"type": "string"},
+			"start": {"type": "string", "format": "date-time"},
+			"attendees": {"type": "array", "items": {"$ref": "Attendee"}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"parent": {"$ref": "Event"},
+			"status": {"type": "string", "enum": ["confirmed", "cancelled"]},
+			"meta": {"type": "any"}
+		}},
+		"Attendee": {"id": "Attendee", "type": "object", "properties": {
+			"email": {"type": "string"},
+			"optional": {"type": "boolean", "default": "false"}
+		}}
+	}
+}`
+
+func TestExampleSchema(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(exampleAPI), &api); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"id":        exampleString,
+		"summary":   exampleString,
+		"start":     exampleDateTime,
+		"attendees": []any{map[string]any{"email": exampleString, "optional": false}},
+		"labels":    map[string]any{"key": exampleString},
+		"status":    "confirmed",
+	}
+	got := NewExampleGenerator(&api).Schema(googleapismodule.Schema{Ref: "Event"})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("example %v, want %v", got, want)
+	}
+
+	request := NewExampleGenerator(&api)
+	request.SkipReadOnly = true
+	if _, ok := request.Schema(googleapismodule.Schema{Ref: "Event"}).(map[string]any)["id"]; ok {
+		t.Fatal("read only field in a request sample")
+	}
+}
+
+func TestExampleParameter(t *testing.T) {
+	tests := []struct {
+		name  string
+		param googleapismodule.Parameter
+		want  any
+	}{
+		{name: "default", param: googleapismodule.Parameter{Type: "integer", Default: "250"}, want: int64(250)},
+		{name: "bounded", param: googleapismodule.Parameter{Type: "integer", Minimum: "5", Maximum: "10"}, want: int64(5)},
+		{name: "number", param: googleapismodule.Parameter{Type: "number", Maximum: "1"}, want: float64(1)},
+		{name: "enum", param: googleapismodule.Parameter{Type: "string", Enum: []string{"asc", "desc"}}, want: "asc"},
+		{name: "int64", param: googleapismodule.Parameter{Type: "string", Format: "int64"}, want: exampleInt64},
+		{name: "field mask", param: googleapismodule.Parameter{Type: "string", Format: "google-fieldmask"}, want: "*"},
+		{name: "repeated", param: googleapismodule.Parameter{Type: "string", Repeated: true}, want: []any{exampleString}},
+		{name: "resource name", param: googleapismodule.Parameter{Type: "string", Pattern: "^projects/[^/]+/topics/[^/]+$"}, want: "projects/example/topics/example"},
+		{name: "complex pattern", param: googleapismodule.Parameter{Type: "string", Pattern: "^[a-z]{3}$"}, want: exampleString},
+	}
+	g := NewExampleGenerator(&googleapismodule.API{})
+	for _, tt := range tests {
+		if got := g.Parameter(tt.param); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parameter = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
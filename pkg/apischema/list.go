@@ -0,0 +1,68 @@
+package apischema
+
+import (
+	"sort"
+	"strings"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// ListField finds the repeated resource field of a list method response,
+// e.g. "items" for calendar.events.list or "files" for drive.files.list.
+// Only arrays of objects are considered. When several match, the field named
+// "items" or after the method resource wins, otherwise the result is ambiguous
+// and ok is false.
+func ListField(api *googleapismodule.API, method googleapismodule.Method) (name string, items googleapismodule.Schema, ok bool) {
+	if method.Response == nil || method.Response.Ref == "" {
+		return "", googleapismodule.Schema{}, false
+	}
+	response, found := api.Schemas[method.Response.Ref]
+	if !found {
+		return "", googleapismodule.Schema{}, false
+	}
+
+	var candidates []string
+	for field, prop := range response.Properties {
+		if prop.Type != "array" || prop.Items == nil {
+			continue
+		}
+		if item := resolveRef(api, *prop.Items); item.Type == "object" {
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", googleapismodule.Schema{}, false
+	}
+	sort.Strings(candidates)
+
+	pick := func(field string) (string, googleapismodule.Schema, bool) {
+		return field, *response.Properties[field].Items, true
+	}
+	if len(candidates) == 1 {
+		return pick(candidates[0])
+	}
+
+	// calendar.events.list -> events
+	var resource string
+	if parts := strings.Split(method.ID, "."); len(parts) >= 2 {
+		resource = parts[len(parts)-2]
+	}
+	for _, field := range candidates {
+		if field == "items" || strings.EqualFold(field, resource) {
+			return pick(field)
+		}
+	}
+	return "", googleapismodule.Schema{}, false
+}
+
+// resolveRef follows $ref chains to the referenced schema
+func resolveRef(api *googleapismodule.API, schema googleapismodule.Schema) googleapismodule.Schema {
+	for i := 0; schema.Ref != "" && i < maxDepth; i++ {
+		ref, ok := api.Schemas[schema.Ref]
+		if !ok {
+			break
+		}
+		schema = ref
+	}
+	return schema
+}
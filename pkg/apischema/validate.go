@@ -0,0 +1,345 @@
+package apischema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// FieldError describes a single invalid field
+type FieldError struct {
+	Field   string `json:"field" title:"Field"`
+	Message string `json:"message" title:"Message"`
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error joins all field errors into a single message
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// maxDepth limits recursion into nested schemas, same as the schema converter
+const maxDepth = 10
+
+// patternCache keeps compiled discovery patterns, they are reused on every request.
+// Patterns which are not RE2 compatible are kept as nil, so they are compiled only once.
+var patternCache sync.Map
+
+// ValidateParameters checks request parameters against a discovery method definition.
+// Path and query parameters are checked against method.Parameters, remaining fields
+// are checked against the request body schema. Returns nil if everything is valid.
+func ValidateParameters(api *googleapismodule.API, method googleapismodule.Method, data map[string]any) error {
+	v := &validator{api: api, methodID: method.ID}
+
+	names := make([]string, 0, len(method.Parameters))
+	for name := range method.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v.parameter(name, method.Parameters[name], data[name])
+	}
+
+	if method.Request != nil && method.Request.Ref != "" {
+		if body, ok := api.Schemas[method.Request.Ref]; ok {
+			v.body(body, data, method.Parameters)
+		}
+	}
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.errors}
+}
+
+type validator struct {
+	api      *googleapismodule.API
+	methodID string
+	errors   []FieldError
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// parameter validates a path or query parameter
+func (v *validator) parameter(name string, param googleapismodule.Parameter, value any) {
+	if isEmpty(value) {
+		if param.Required {
+			v.fail(name, "required %s parameter is missing", param.Location)
+		}
+		return
+	}
+
+	if values, ok := value.([]any); ok {
+		if !param.Repeated && param.Type != "array" {
+			v.fail(name, "expected a single value, got array")
+			return
+		}
+		item := param
+		if param.Items != nil {
+			item = *param.Items
+		}
+		for i, el := range values {
+			v.scalar(fmt.Sprintf("%s[%d]", name, i), item.Type, item.Format, item.Enum, item.Pattern, item.Minimum, item.Maximum, el)
+		}
+		return
+	}
+
+	v.scalar(name, param.Type, param.Format, param.Enum, param.Pattern, param.Minimum, param.Maximum, value)
+}
+
+// body validates body fields against the request schema
+func (v *validator) body(schema googleapismodule.Schema, data map[string]any, params map[string]googleapismodule.Parameter) {
+	schema = v.resolve(schema)
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, isParam := params[name]; isParam {
+			// parameters shadow body fields with the same name
+			continue
+		}
+		v.value(name, schema.Properties[name], data[name], 0)
+	}
+}
+
+// value validates an arbitrary JSON value against a discovery schema
+func (v *validator) value(field string, schema googleapismodule.Schema, value any, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	schema = v.resolve(schema)
+
+	if isEmpty(value) {
+		if v.required(schema) {
+			v.fail(field, "required field is missing")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.fail(field, "expected object, got %s", typeName(value))
+			return
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v.value(field+"."+name, schema.Properties[name], obj[name], depth+1)
+		}
+		if schema.AdditionalProperties != nil {
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				if _, known := schema.Properties[k]; !known {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				v.value(field+"."+k, *schema.AdditionalProperties, obj[k], depth+1)
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			v.fail(field, "expected array, got %s", typeName(value))
+			return
+		}
+		if schema.Items == nil {
+			return
+		}
+		for i, el := range arr {
+			v.value(fmt.Sprintf("%s[%d]", field, i), *schema.Items, el, depth+1)
+		}
+	case "any", "":
+		return
+	default:
+		v.scalar(field, schema.Type, schema.Format, schema.Enum, schema.Pattern, schema.Minimum, schema.Maximum, value)
+	}
+}
+
+// scalar validates string, integer, number and boolean values
+func (v *validator) scalar(field, typ, format string, enum []string, pattern, minimum, maximum string, value any) {
+	switch typ {
+	case "integer", "number":
+		n, ok := toNumber(value)
+		if !ok {
+			v.fail(field, "expected %s, got %s", typ, typeName(value))
+			return
+		}
+		if typ == "integer" && n != float64(int64(n)) {
+			v.fail(field, "expected integer, got %v", n)
+			return
+		}
+		if lo, err := strconv.ParseFloat(minimum, 64); err == nil && n < lo {
+			v.fail(field, "must be greater than or equal to %s", minimum)
+		}
+		if hi, err := strconv.ParseFloat(maximum, 64); err == nil && n > hi {
+			v.fail(field, "must be less than or equal to %s", maximum)
+		}
+		return
+	case "boolean":
+		switch b := value.(type) {
+		case bool:
+		case string:
+			if _, err := strconv.ParseBool(b); err != nil {
+				v.fail(field, "expected boolean, got %q", b)
+			}
+		default:
+			v.fail(field, "expected boolean, got %s", typeName(value))
+		}
+		return
+	}
+
+	// strings; int64 values are encoded as strings by Google APIs
+	var s string
+	switch val := value.(type) {
+	case string:
+		s = val
+	case float64, int, int64, bool:
+		s = fmt.Sprintf("%v", val)
+	default:
+		v.fail(field, "expected string, got %s", typeName(value))
+		return
+	}
+
+	if isIntegerFormat(format) {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			if _, err := strconv.ParseUint(s, 10, 64); err != nil {
+				v.fail(field, "expected %s encoded as string, got %q", format, s)
+				return
+			}
+		}
+	}
+
+	if len(enum) > 0 && !contains(enum, s) {
+		v.fail(field, "must be one of %s", strings.Join(enum, ", "))
+		return
+	}
+
+	if pattern != "" {
+		if re := compilePattern(pattern); re != nil && !re.MatchString(s) {
+			v.fail(field, "does not match pattern %s", pattern)
+		}
+	}
+}
+
+// required reports whether a body field is required for the validated method
+func (v *validator) required(schema googleapismodule.Schema) bool {
+	if schema.Annotations == nil {
+		return false
+	}
+	return contains(schema.Annotations.Required, v.methodID)
+}
+
+// resolve follows $ref keeping annotations of the referencing property
+func (v *validator) resolve(schema googleapismodule.Schema) googleapismodule.Schema {
+	if schema.Ref == "" {
+		return schema
+	}
+	resolved, ok := v.api.Schemas[schema.Ref]
+	if !ok {
+		return googleapismodule.Schema{Type: "any"}
+	}
+	if schema.Annotations != nil {
+		resolved.Annotations = schema.Annotations
+	}
+	return resolved
+}
+
+func compilePattern(pattern string) *regexp.Regexp {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// pattern is not RE2 compatible, skip the check
+		re = nil
+	}
+	patternCache.Store(pattern, re)
+	return re
+}
+
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	if s, ok := value.(string); ok {
+		return s == ""
+	}
+	return false
+}
+
+func isIntegerFormat(format string) bool {
+	switch format {
+	case "int64", "uint64":
+		return true
+	}
+	return false
+}
+
+func toNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, float32, int, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func contains(list []string, s string) bool {
+	for _, el := range list {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
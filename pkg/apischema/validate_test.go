@@ -0,0 +1,140 @@
+package apischema
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+const validateAPI = `{
+	"id": "test:v1",
+	"name": "test",
+	"schemas": {
+		"Item": {
+			"id": "Item",
+			"type": "object",
+			"properties": {
+				"title": {"type": "string", "annotations": {"required": ["test.items.insert"]}},
+				"size": {"type": "integer", "format": "int32", "minimum": "1", "maximum": "10"},
+				"kind": {"type": "string", "enum": ["small", "large"]},
+				"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}}
+			}
+		}
+	},
+	"resources": {
+		"items": {
+			"methods": {
+				"insert": {
+					"id": "test.items.insert",
+					"path": "projects/{projectId}/items",
+					"httpMethod": "POST",
+					"parameters": {
+						"projectId": {"type": "string", "location": "path", "required": true, "pattern": "^[a-z][a-z0-9-]*$"},
+						"maxResults": {"type": "integer", "location": "query", "minimum": "1", "maximum": "100"},
+						"order": {"type": "string", "location": "query", "enum": ["asc", "desc"]},
+						"id": {"type": "string", "location": "query", "format": "int64"},
+						"fields": {"type": "string", "location": "query", "repeated": true, "enum": ["a", "b"]}
+					},
+					"request": {"$ref": "Item"}
+				}
+			}
+		}
+	}
+}`
+
+func TestValidateParameters(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(validateAPI), &api); err != nil {
+		t.Fatal(err)
+	}
+	method := api.Resources["items"].Methods["insert"]
+
+	valid := func() map[string]any {
+		return map[string]any{"projectId": "my-project", "title": "Item"}
+	}
+	with := func(key string, value any) map[string]any {
+		data := valid()
+		data[key] = value
+		return data
+	}
+	without := func(key string) map[string]any {
+		data := valid()
+		delete(data, key)
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		data   map[string]any
+		fields []string
+	}{
+		{name: "valid", data: valid()},
+		{name: "valid with optional fields", data: with("maxResults", float64(100))},
+		{name: "missing path parameter", data: without("projectId"), fields: []string{"projectId"}},
+		{name: "missing required body field", data: without("title"), fields: []string{"title"}},
+		{name: "pattern mismatch", data: with("projectId", "My_Project"), fields: []string{"projectId"}},
+		{name: "pattern in array items", data: with("tags", []any{"ok", "Not-OK"}), fields: []string{"tags[1]"}},
+		{name: "enum", data: with("order", "random"), fields: []string{"order"}},
+		{name: "enum in body", data: with("kind", "medium"), fields: []string{"kind"}},
+		{name: "repeated enum", data: with("fields", []any{"a", "c"}), fields: []string{"fields[1]"}},
+		{name: "below minimum", data: with("maxResults", float64(0)), fields: []string{"maxResults"}},
+		{name: "above maximum", data: with("size", float64(11)), fields: []string{"size"}},
+		{name: "numeric string in range", data: with("maxResults", "50")},
+		{name: "not an integer", data: with("size", 1.5), fields: []string{"size"}},
+		{name: "int64 string", data: with("id", "9007199254740993")},
+		{name: "invalid int64 string", data: with("id", "12a"), fields: []string{"id"}},
+		{name: "array for single parameter", data: with("order", []any{"asc"}), fields: []string{"order"}},
+		{
+			name:   "all errors are reported",
+			data:   map[string]any{"projectId": "1bad", "order": "random", "size": float64(0)},
+			fields: []string{"order", "projectId", "size", "title"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParameters(&api, method, tt.data)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			var fields []string
+			for _, f := range verr.Fields {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("invalid fields %v, want %v: %v", fields, tt.fields, err)
+			}
+		})
+	}
+}
+
+func TestCompilePatternCachesFailures(t *testing.T) {
+	// lookahead is not supported by RE2
+	const pattern = `^(?!-)[a-z-]+$`
+	if re := compilePattern(pattern); re != nil {
+		t.Fatalf("pattern %s compiled", pattern)
+	}
+	cached, ok := patternCache.Load(pattern)
+	if !ok || cached.(*regexp.Regexp) != nil {
+		t.Fatalf("failed pattern was not cached as nil: %v", cached)
+	}
+	if re := compilePattern(pattern); re != nil {
+		t.Fatal("cached failure returned a regexp")
+	}
+
+	if re := compilePattern(`^[a-z]+$`); re == nil || !re.MatchString("abc") {
+		t.Fatal("valid pattern was not compiled")
+	}
+}
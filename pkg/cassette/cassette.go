@@ -0,0 +1,141 @@
+// Package cassette records HTTP interactions with Google APIs to disk and replays them,
+// for deterministic integration tests and demo environments without credentials.
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+const (
+	// ModeEnv follows the CASSETTE_MODE environment variable
+	ModeEnv = "env"
+	// ModeOff sends requests as is
+	ModeOff = "off"
+	// ModeRecord sends requests and stores every interaction
+	ModeRecord = "record"
+	// ModeReplay answers from stored interactions only, unknown requests fail
+	ModeReplay = "replay"
+	// ModeAuto replays stored interactions and records the rest
+	ModeAuto = "auto"
+)
+
+const (
+	// ModeEnvVar selects the module-wide mode, components may override it
+	ModeEnvVar = "CASSETTE_MODE"
+	// DirEnvVar is the default cassette directory
+	DirEnvVar = "CASSETTE_DIR"
+)
+
+// Settings selects the cassette mode of a component
+type Settings struct {
+	Mode string `json:"mode" title:"Mode" default:"env" enum:"env,off,record,replay,auto" enumTitles:"From environment,Off,Record,Replay,Replay or record" description:"Record real calls to disk with secrets redacted or replay them. From environment follows CASSETTE_MODE"`
+	Dir  string `json:"dir,omitempty" title:"Directory" description:"Directory of recorded interactions, defaults to CASSETTE_DIR"`
+}
+
+type settingsKey struct{}
+
+// WithSettings overrides the environment for requests made with ctx
+func WithSettings(ctx context.Context, settings Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, settings)
+}
+
+// SettingsFrom returns the settings of ctx set by WithSettings
+func SettingsFrom(ctx context.Context) Settings {
+	settings, _ := ctx.Value(settingsKey{}).(Settings)
+	return settings
+}
+
+// resolve returns the effective mode and directory of a request
+func resolve(ctx context.Context) (mode, dir string) {
+	settings := SettingsFrom(ctx)
+
+	mode = settings.Mode
+	if mode == "" || mode == ModeEnv {
+		mode = os.Getenv(ModeEnvVar)
+	}
+	if mode == "" {
+		mode = ModeOff
+	}
+
+	dir = settings.Dir
+	if dir == "" {
+		dir = os.Getenv(DirEnvVar)
+	}
+	return mode, dir
+}
+
+// Transport records or replays requests depending on the mode of each request
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with recording and replay, http.DefaultTransport is used if base is nil
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mode, dir := resolve(req.Context())
+	switch mode {
+	case ModeOff:
+		return t.Base.RoundTrip(req)
+	case ModeRecord, ModeReplay, ModeAuto:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("cassette mode %s requires a directory, set %s", mode, DirEnvVar)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+		_ = req.Body.Close()
+	}
+	recorded := newRequest(req, body)
+	path := interactionPath(dir, recorded)
+
+	if mode != ModeRecord {
+		in, err := load(path)
+		if err == nil {
+			return in.Response.httpResponse(req), nil
+		}
+		if mode == ModeReplay {
+			return nil, fmt.Errorf("no recorded interaction for %s %s: %w", recorded.Method, recorded.URL, err)
+		}
+	}
+
+	// the original request is sent, only the recording is redacted
+	if req.Body != nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := save(path, Interaction{Request: recorded, Response: newResponse(resp, respBody)}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
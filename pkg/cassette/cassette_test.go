@@ -0,0 +1,146 @@
+package cassette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"client_secret":"s3cret","name":"event"}` {
+			t.Errorf("server got body %s, want the original", body)
+		}
+		if r.Header.Get("Authorization") != "Bearer live-token" {
+			t.Errorf("server got authorization %q, want the original", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = w.Write([]byte(`{"access_token":"ya29.secret","id":"e1"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: NewTransport(nil)}
+	call := func(mode, token, body string) (*http.Response, error) {
+		t.Helper()
+		ctx := WithSettings(context.Background(), Settings{Mode: mode, Dir: dir})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/events?key="+token, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		return client.Do(req)
+	}
+
+	resp, err := call(ModeRecord, "live-token", `{"client_secret":"s3cret","name":"event"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"access_token":"ya29.secret","id":"e1"}` {
+		t.Fatalf("recording changed the response to %s", body)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("recorded files %v, %v", files, err)
+	}
+	recording, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"live-token", "s3cret", "ya29.secret", "session=abc"} {
+		if strings.Contains(string(recording), secret) {
+			t.Fatalf("recording contains %q:\n%s", secret, recording)
+		}
+	}
+
+	// other credentials and key order match the same recording
+	resp, err = call(ModeReplay, "other-token", `{"name":"event","client_secret":"other"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != `{"access_token":"REDACTED","id":"e1"}` {
+		t.Fatalf("replayed %d %s", resp.StatusCode, body)
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("replay reached the server, %d requests", requests.Load())
+	}
+
+	if _, err := call(ModeReplay, "live-token", `{"name":"other"}`); err == nil {
+		t.Fatal("expected an error replaying an unknown request")
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("failed replay reached the server, %d requests", requests.Load())
+	}
+}
+
+func TestAutoMode(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer server.Close()
+
+	ctx := WithSettings(context.Background(), Settings{Mode: ModeAuto, Dir: t.TempDir()})
+	client := &http.Client{Transport: NewTransport(nil)}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/ping", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+			t.Fatalf("request %d got %s", i, body)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("auto mode sent %d requests, want 1", requests.Load())
+	}
+}
+
+func TestModeRequiresDir(t *testing.T) {
+	t.Setenv(DirEnvVar, "")
+	ctx := WithSettings(context.Background(), Settings{Mode: ModeReplay})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTransport(nil).RoundTrip(req); err == nil {
+		t.Fatal("expected an error without cassette directory")
+	}
+}
+
+func TestNormalizeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "json", contentType: "application/json", body: `{"b":1,"a":{"refresh_token":"x"}}`, want: `{"a":{"refresh_token":"REDACTED"},"b":1}`},
+		{name: "form", contentType: "application/x-www-form-urlencoded; charset=utf-8", body: "grant_type=refresh_token&client_secret=x&code=y", want: "client_secret=REDACTED&code=REDACTED&grant_type=refresh_token"},
+		{name: "text", contentType: "text/plain", body: "plain", want: "plain"},
+		{name: "empty", body: " "},
+	}
+	for _, tt := range tests {
+		if got := normalizeBody(tt.contentType, []byte(tt.body)); string(got) != tt.want {
+			t.Errorf("%s: normalizeBody = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
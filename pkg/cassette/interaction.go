@@ -0,0 +1,251 @@
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders are never stored
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Goog-Api-Key", "Proxy-Authorization"}
+
+// sensitiveFields are JSON fields never stored, e.g. tokens returned by token endpoints
+var sensitiveFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"client_secret": true,
+	"private_key":   true,
+	"assertion":     true,
+	"subject_token": true,
+	"accessToken":   true,
+	"refreshToken":  true,
+	"signedJwt":     true,
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// Request is a redacted request, method, URL and body identify the interaction
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Response is a redacted response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body keeps JSON inline so recordings stay readable, other content is stored as a string
+type Body []byte
+
+// MarshalJSON implements json.Marshaler
+func (b Body) MarshalJSON() ([]byte, error) {
+	if len(b) == 0 {
+		return []byte(`""`), nil
+	}
+	if json.Valid(b) {
+		return b, nil
+	}
+	return json.MarshalWithOption(string(b), json.DisableHTMLEscape())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	*b = compact.Bytes()
+	return nil
+}
+
+func newRequest(req *http.Request, body []byte) Request {
+	return Request{
+		Method: req.Method,
+		URL:    telemetry.RedactURL(req.URL),
+		Header: redactHeader(req.Header),
+		Body:   normalizeBody(req.Header.Get("Content-Type"), body),
+	}
+}
+
+func newResponse(resp *http.Response, body []byte) Response {
+	header := redactHeader(resp.Header)
+	// the stored body is re-encoded, so its length may differ
+	header.Del("Content-Length")
+	return Response{
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Body:       normalizeBody(resp.Header.Get("Content-Type"), body),
+	}
+}
+
+// httpResponse builds a response to req from the recording
+func (r Response) httpResponse(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// interactionPath names a recording after the request method, host and a hash of
+// method, redacted URL and normalized body, so secrets don't affect matching
+func interactionPath(dir string, req Request) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", req.Method, req.URL)
+	_, _ = h.Write(req.Body)
+	sum := hex.EncodeToString(h.Sum(nil))[:16]
+
+	host := "unknown"
+	if u, err := url.Parse(req.URL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s-%s.json", strings.ToLower(req.Method), host, sum))
+}
+
+func load(path string) (*Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var in Interaction
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("cassette %s is corrupted: %w", path, err)
+	}
+	return &in, nil
+}
+
+// save atomically writes an interaction
+func save(path string, in Interaction) error {
+	in.RecordedAt = time.Now().UTC()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(in); err != nil {
+		return fmt.Errorf("unable to encode cassette: %w", err)
+	}
+	data := buf.Bytes()
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create cassette directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write cassette: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write cassette: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	result := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if result.Get(name) != "" {
+			result.Set(name, redacted)
+		}
+	}
+	return result
+}
+
+// normalizeBody redacts secrets and gives equivalent bodies the same bytes:
+// JSON is re-encoded with sorted keys, forms are re-encoded sorted by name
+func normalizeBody(contentType string, body []byte) Body {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		for _, name := range telemetry.SensitiveParams {
+			if form.Has(name) {
+				form.Set(name, redacted)
+			}
+		}
+		return Body(form.Encode())
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	normalized, err := json.MarshalWithOption(redactJSON(v), json.DisableHTMLEscape())
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+func redactJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, el := range val {
+			if sensitiveFields[k] {
+				val[k] = redacted
+				continue
+			}
+			val[k] = redactJSON(el)
+		}
+	case []any:
+		for i, el := range val {
+			val[i] = redactJSON(el)
+		}
+	}
+	return v
+}
@@ -0,0 +1,243 @@
+// Package clientpool shares authenticated Google clients between messages, so parsed keys,
+// minted tokens and open connections are reused instead of being created per request.
+package clientpool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	// clients not used for this long are closed
+	DefaultIdleTimeout = 10 * time.Minute
+	sweepInterval      = time.Minute
+)
+
+var (
+	poolsMu sync.Mutex
+	pools   []closer
+)
+
+type closer interface {
+	Close() error
+}
+
+type entry[T any] struct {
+	client   T
+	refs     int
+	lastUsed time.Time
+}
+
+// Pool shares clients by key. Clients idle longer than the idle timeout are closed,
+// clients in use are never closed by eviction.
+type Pool[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*entry[T]
+	// creating dedupes concurrent creation of the same client
+	creating map[string]*sync.WaitGroup
+
+	idleTimeout time.Duration
+	closeFn     func(T) error
+
+	sweeping bool
+	stop     chan struct{}
+	closed   bool
+}
+
+// New returns a pool closing clients with closeFn, which may be nil for clients without resources.
+// The pool is closed by CloseAll.
+func New[T any](idleTimeout time.Duration, closeFn func(T) error) *Pool[T] {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	p := &Pool[T]{
+		entries:     make(map[string]*entry[T]),
+		creating:    make(map[string]*sync.WaitGroup),
+		idleTimeout: idleTimeout,
+		closeFn:     closeFn,
+		stop:        make(chan struct{}),
+	}
+	poolsMu.Lock()
+	pools = append(pools, p)
+	poolsMu.Unlock()
+	return p
+}
+
+// Acquire returns the client of key, created with create if there is none.
+// The client must be released when the caller is done with it.
+// create gets a clean context rather than ctx: the client outlives the message and must not carry
+// values of the first caller, e.g. its trace or call attributes. Values a client depends on belong in key.
+func (p *Pool[T]) Acquire(ctx context.Context, key string, create func(ctx context.Context) (T, error)) (T, func(), error) {
+	var zero T
+	for {
+		if err := ctx.Err(); err != nil {
+			return zero, nil, err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return zero, nil, errors.New("client pool is closed")
+		}
+		if e, ok := p.entries[key]; ok {
+			e.refs++
+			e.lastUsed = time.Now()
+			p.mu.Unlock()
+			return e.client, p.releaseFunc(key, e), nil
+		}
+		wg, busy := p.creating[key]
+		if !busy {
+			wg = &sync.WaitGroup{}
+			wg.Add(1)
+			p.creating[key] = wg
+			p.mu.Unlock()
+			break
+		}
+		p.mu.Unlock()
+		// another message is creating the client
+		wg.Wait()
+	}
+
+	client, err := create(context.Background())
+
+	p.mu.Lock()
+	wg := p.creating[key]
+	delete(p.creating, key)
+	defer wg.Done()
+
+	if err != nil {
+		p.mu.Unlock()
+		return zero, nil, err
+	}
+	if p.closed {
+		p.mu.Unlock()
+		p.closeClient(client)
+		return zero, nil, errors.New("client pool is closed")
+	}
+	e := &entry[T]{client: client, refs: 1, lastUsed: time.Now()}
+	p.entries[key] = e
+	p.startSweep()
+	p.mu.Unlock()
+	return client, p.releaseFunc(key, e), nil
+}
+
+func (p *Pool[T]) releaseFunc(key string, e *entry[T]) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			e.refs--
+			e.lastUsed = time.Now()
+		})
+	}
+}
+
+// startSweep runs eviction in the background while the pool has clients, must be called with lock held
+func (p *Pool[T]) startSweep() {
+	if p.sweeping {
+		return
+	}
+	p.sweeping = true
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case now := <-ticker.C:
+				if !p.sweep(now) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// sweep closes idle clients, returns false when the pool is empty and sweeping stopped
+func (p *Pool[T]) sweep(now time.Time) bool {
+	var idle []T
+
+	p.mu.Lock()
+	for key, e := range p.entries {
+		if e.refs <= 0 && now.Sub(e.lastUsed) > p.idleTimeout {
+			delete(p.entries, key)
+			idle = append(idle, e.client)
+		}
+	}
+	empty := len(p.entries) == 0
+	if empty {
+		p.sweeping = false
+	}
+	p.mu.Unlock()
+
+	for _, client := range idle {
+		p.closeClient(client)
+	}
+	return !empty
+}
+
+// Len returns the number of pooled clients
+func (p *Pool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// Close closes all clients, including those in use, and rejects further Acquire calls
+func (p *Pool[T]) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	entries := p.entries
+	p.entries = make(map[string]*entry[T])
+	p.mu.Unlock()
+
+	var errs []error
+	for _, e := range entries {
+		errs = append(errs, p.closeClient(e.client))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pool[T]) closeClient(client T) error {
+	if p.closeFn == nil {
+		return nil
+	}
+	return p.closeFn(client)
+}
+
+// CloseAll closes every pool, e.g. on module shutdown
+func CloseAll() error {
+	poolsMu.Lock()
+	all := pools
+	pools = nil
+	poolsMu.Unlock()
+
+	var errs []error
+	for _, p := range all {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Key fingerprints the values identifying a client, e.g. credentials, scopes, subject and token,
+// so secrets are never kept in pool keys
+func Key(values ...any) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, v := range values {
+		_ = enc.Encode(v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
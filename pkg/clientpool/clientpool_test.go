@@ -0,0 +1,164 @@
+package clientpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type client struct {
+	id     int
+	closed atomic.Bool
+}
+
+func newTestPool(t *testing.T) (*Pool[*client], func(ctx context.Context) (*client, error), *atomic.Int32) {
+	t.Helper()
+	var created atomic.Int32
+	pool := New[*client](time.Minute, func(c *client) error {
+		c.closed.Store(true)
+		return nil
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+	create := func(ctx context.Context) (*client, error) {
+		// clients take a while to create, e.g. to parse keys or dial
+		time.Sleep(10 * time.Millisecond)
+		return &client{id: int(created.Add(1))}, nil
+	}
+	return pool, create, &created
+}
+
+func TestAcquireSharesClients(t *testing.T) {
+	pool, create, created := newTestPool(t)
+
+	var wg sync.WaitGroup
+	clients := make([]*client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, release, err := pool.Acquire(context.Background(), Key("config", i%2), create)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			clients[i] = c
+		}()
+	}
+	wg.Wait()
+
+	if n := created.Load(); n != 2 {
+		t.Fatalf("created %d clients, want one per key", n)
+	}
+	for i, c := range clients {
+		if c != clients[i%2] {
+			t.Fatalf("client %d differs from the client of its key", i)
+		}
+	}
+	if pool.Len() != 2 {
+		t.Fatalf("pool has %d clients, want 2", pool.Len())
+	}
+}
+
+type callerKey struct{}
+
+func TestAcquireCreatesWithCleanContext(t *testing.T) {
+	pool, _, _ := newTestPool(t)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), callerKey{}, "first caller"))
+	var createCtx context.Context
+	_, release, err := pool.Acquire(ctx, Key("config"), func(ctx context.Context) (*client, error) {
+		createCtx = ctx
+		return &client{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	cancel()
+
+	if createCtx.Value(callerKey{}) != nil {
+		t.Fatal("client was created with values of the caller")
+	}
+	if createCtx.Err() != nil {
+		t.Fatal("client context was cancelled with the caller")
+	}
+
+	if _, _, err := pool.Acquire(ctx, Key("config"), nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for a cancelled caller, got %v", err)
+	}
+}
+
+func TestAcquireCreateError(t *testing.T) {
+	pool, create, _ := newTestPool(t)
+
+	failed := errors.New("invalid credentials")
+	if _, _, err := pool.Acquire(context.Background(), Key("config"), func(context.Context) (*client, error) {
+		return nil, failed
+	}); !errors.Is(err, failed) {
+		t.Fatalf("expected create error, got %v", err)
+	}
+	// failures are not cached
+	if _, release, err := pool.Acquire(context.Background(), Key("config"), create); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+}
+
+func TestSweepClosesIdleClients(t *testing.T) {
+	pool, create, _ := newTestPool(t)
+
+	idle, release, err := pool.Acquire(context.Background(), Key("idle"), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release() // releasing twice is harmless
+
+	busy, release, err := pool.Acquire(context.Background(), Key("busy"), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	if !pool.sweep(time.Now().Add(2 * time.Minute)) {
+		t.Fatal("sweeping stopped with a client in use")
+	}
+	if !idle.closed.Load() || busy.closed.Load() {
+		t.Fatalf("idle closed %v, busy closed %v, want only the idle client closed", idle.closed.Load(), busy.closed.Load())
+	}
+	if pool.Len() != 1 {
+		t.Fatalf("pool has %d clients, want 1", pool.Len())
+	}
+}
+
+func TestClose(t *testing.T) {
+	pool, create, _ := newTestPool(t)
+
+	c, _, err := pool.Acquire(context.Background(), Key("config"), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.closed.Load() {
+		t.Fatal("client in use was not closed")
+	}
+	if _, _, err := pool.Acquire(context.Background(), Key("config"), create); err == nil {
+		t.Fatal("closed pool returned a client")
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("config", nil) == Key("config", "token") {
+		t.Fatal("different values share a key")
+	}
+	if Key(map[string]string{"a": "1", "b": "2"}) != Key(map[string]string{"b": "2", "a": "1"}) {
+		t.Fatal("equal values have different keys")
+	}
+}
@@ -0,0 +1,189 @@
+// Package codegen generates static typed components from discovery methods,
+// so production flows don't depend on discovery documents at runtime
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"strings"
+	"text/template"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// File is a generated source file, Path is relative to the output directory
+type File struct {
+	Path    string
+	Content []byte
+}
+
+type param struct {
+	Name  string
+	Field string
+	Type  string
+	Tags  string
+}
+
+type componentData struct {
+	Package       string
+	ComponentName string
+	Service       string
+	MethodID      string
+	HTTPMethod    string
+	BaseURL       string
+	Path          string
+	Scopes        []string
+	Title         string
+	Info          string
+	APIName       string
+
+	Params      []param
+	PathParams  []param
+	QueryParams []param
+	BodyType    string
+
+	ResultType    string
+	ResultElem    string
+	ResultZero    string
+	ResultPointer bool
+
+	Types string
+}
+
+// PackageDir returns the directory of a generated component relative to the output
+// directory, e.g. calendar/events-list for events.list of calendar:v3
+func PackageDir(api *googleapismodule.API, methodName string) string {
+	return path.Join(api.Name, strings.ToLower(strings.ReplaceAll(methodName, ".", "-")))
+}
+
+// Generate renders a component package per method, methods are named like in
+// google_api_call settings, e.g. events.list
+func Generate(api *googleapismodule.API, methodNames []string) ([]File, error) {
+	methods := make(map[string]googleapismodule.Method)
+	for _, m := range api.GetAllMethods() {
+		methods[m.FullName] = m.Method
+	}
+
+	var files []File
+	for _, name := range methodNames {
+		method, ok := methods[name]
+		if !ok {
+			return nil, fmt.Errorf("method %s not found in %s", name, api.ID)
+		}
+
+		data := newComponentData(api, name, method)
+		dir := PackageDir(api, name)
+
+		component, err := render(componentTemplate, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate %s: %w", name, err)
+		}
+		files = append(files, File{Path: path.Join(dir, path.Base(dir)+".go"), Content: component})
+
+		if data.Types != "" {
+			types, err := render(typesTemplate, data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to generate types of %s: %w", name, err)
+			}
+			files = append(files, File{Path: path.Join(dir, "types.go"), Content: types})
+		}
+	}
+	return files, nil
+}
+
+func newComponentData(api *googleapismodule.API, name string, method googleapismodule.Method) componentData {
+	baseURL := api.BaseUrl
+	if baseURL == "" {
+		baseURL = api.RootUrl + api.ServicePath
+	}
+
+	data := componentData{
+		Package:       strings.ReplaceAll(path.Base(PackageDir(api, name)), "-", "_"),
+		ComponentName: api.Name + "_" + strings.ReplaceAll(name, ".", "_"),
+		Service:       api.ID,
+		MethodID:      method.ID,
+		HTTPMethod:    method.HttpMethod,
+		BaseURL:       baseURL,
+		Path:          method.Path,
+		Scopes:        method.Scopes,
+		Title:         title(api.Name) + " " + title(name),
+		Info:          shortDescription(method.Description),
+		APIName:       api.Name,
+	}
+
+	types := newTypeWriter(api)
+
+	// fields already declared by the template
+	used := map[string]bool{"Context": true, "Config": true, "Token": true, "Body": true}
+	for _, pname := range parameterNames(method) {
+		p := method.Parameters[pname]
+		prm := param{
+			Name:  pname,
+			Field: uniqueName(exportedName(pname), used),
+			Type:  paramType(p),
+			Tags:  tags(pname, p.Required, p.Description, p.Enum, p.Default),
+		}
+		data.Params = append(data.Params, prm)
+		if p.Location == "path" {
+			data.PathParams = append(data.PathParams, prm)
+		} else {
+			data.QueryParams = append(data.QueryParams, prm)
+		}
+	}
+
+	if method.Request != nil && method.Request.Ref != "" {
+		// body is required, so it's a value rather than a pointer
+		data.BodyType = strings.TrimPrefix(types.ref(method.Request.Ref), "*")
+	}
+
+	if method.Response != nil && method.Response.Ref != "" {
+		data.ResultType = types.ref(method.Response.Ref)
+		data.ResultElem = strings.TrimPrefix(data.ResultType, "*")
+		data.ResultPointer = data.ResultElem != data.ResultType
+		data.ResultZero = zeroValue(data.ResultType)
+	}
+
+	data.Types = types.source()
+	return data
+}
+
+// parameterNames orders parameters like parameterOrder, remaining ones sorted
+func parameterNames(method googleapismodule.Method) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string{}, method.ParameterOrder...), sortedKeys(method.Parameters)...) {
+		if _, ok := method.Parameters[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func zeroValue(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "*"), strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["), typ == "any":
+		return "nil"
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"), typ == "float64":
+		return "0"
+	}
+	return typ + "{}"
+}
+
+func render(t *template.Template, data componentData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code is invalid: %w", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,145 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// collidingAPI has schemas named like identifiers of the component template,
+// e.g. Service of Cloud Run and Service Management
+const collidingAPI = `{
+	"id": "run:v1",
+	"name": "run",
+	"version": "v1",
+	"rootUrl": "https://run.googleapis.com/",
+	"servicePath": "",
+	"schemas": {
+		"Service": {"id": "Service", "type": "object", "properties": {
+			"metadata": {"$ref": "Path"},
+			"spec": {"$ref": "Settings"},
+			"status": {"$ref": "Response"}
+		}},
+		"Path": {"id": "Path", "type": "object", "properties": {"name": {"type": "string"}}},
+		"Settings": {"id": "Settings", "type": "object", "properties": {"scopes": {"$ref": "Scopes"}}},
+		"Scopes": {"id": "Scopes", "type": "array", "items": {"type": "string"}},
+		"Response": {"id": "Response", "type": "object", "properties": {
+			"baseUrl": {"$ref": "BaseURL"},
+			"method": {"$ref": "MethodID"},
+			"http": {"$ref": "HTTPMethod"},
+			"component": {"$ref": "ComponentName"},
+			"ports": {"type": "array", "items": {"$ref": "RequestPort"}},
+			"error": {"$ref": "Error"},
+			"context": {"$ref": "Context"}
+		}},
+		"BaseURL": {"id": "BaseURL", "type": "string"},
+		"MethodID": {"id": "MethodID", "type": "string"},
+		"HTTPMethod": {"id": "HTTPMethod", "type": "object", "properties": {"verb": {"type": "string"}}},
+		"ComponentName": {"id": "ComponentName", "type": "string"},
+		"RequestPort": {"id": "RequestPort", "type": "object", "properties": {"port": {"$ref": "ResponsePort"}}},
+		"ResponsePort": {"id": "ResponsePort", "type": "object", "properties": {"error": {"$ref": "ErrorPort"}}},
+		"ErrorPort": {"id": "ErrorPort", "type": "object", "properties": {"code": {"type": "integer", "format": "int32"}}},
+		"Error": {"id": "Error", "type": "object", "properties": {"message": {"type": "string"}}},
+		"Context": {"id": "Context", "type": "object", "properties": {"id": {"type": "string"}}},
+		"Component": {"id": "Component", "type": "object", "properties": {"name": {"type": "string"}, "request": {"$ref": "Request"}}},
+		"Request": {"id": "Request", "type": "object", "properties": {"id": {"type": "string"}}}
+	},
+	"resources": {
+		"services": {
+			"methods": {
+				"create": {
+					"id": "run.services.create",
+					"path": "v1/{+parent}/services",
+					"httpMethod": "POST",
+					"parameters": {"parent": {"type": "string", "location": "path", "required": true}},
+					"parameterOrder": ["parent"],
+					"request": {"$ref": "Service"},
+					"response": {"$ref": "Component"},
+					"scopes": ["https://www.googleapis.com/auth/cloud-platform"]
+				}
+			}
+		}
+	}
+}`
+
+func TestGenerateAvoidsTemplateNames(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(collidingAPI), &api); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Generate(&api, []string{"services.create"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("generated %d files, want component and types", len(files))
+	}
+
+	// top level identifiers of the package, declared once
+	fset := token.NewFileSet()
+	declared := make(map[string]string)
+	for _, f := range files {
+		file, err := parser.ParseFile(fset, f.Path, f.Content, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", f.Path, err)
+		}
+		for _, name := range topLevelNames(file) {
+			if other, ok := declared[name]; ok {
+				t.Errorf("%s redeclared in %s, first declared in %s", name, f.Path, other)
+			}
+			declared[name] = f.Path
+		}
+	}
+
+	for name := range reservedNames {
+		if _, ok := declared[name]; !ok {
+			t.Errorf("reserved name %s is not declared by the template", name)
+		}
+		if _, ok := declared[name+"Schema"]; !ok {
+			t.Errorf("schema %s was not renamed to %sSchema", name, name)
+		}
+	}
+
+	component := string(files[0].Content)
+	if path.Base(files[0].Path) != "services-create.go" {
+		t.Fatalf("unexpected component file %s", files[0].Path)
+	}
+	for _, want := range []string{"Body ServiceSchema", "func (g *Component) call(ctx context.Context, req Request) (*ComponentSchema, error)"} {
+		if !strings.Contains(strings.Join(strings.Fields(component), " "), want) {
+			t.Errorf("component doesn't contain %q", want)
+		}
+	}
+}
+
+func topLevelNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name != "init" {
+				names = append(names, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.Name != "_" {
+							names = append(names, n.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,215 @@
+package codegen
+
+import "text/template"
+
+var componentTemplate = template.Must(template.New("component").Parse(`// Code generated by googleapis-module discovery generate. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/tiny-systems/googleapis-module/components/etc"
+	"github.com/tiny-systems/googleapis-module/pkg/ratelimit"
+	"github.com/tiny-systems/googleapis-module/pkg/rest"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
+	"github.com/tiny-systems/module/api/v1alpha1"
+	"github.com/tiny-systems/module/module"
+	"github.com/tiny-systems/module/registry"
+)
+
+const (
+	ComponentName = {{printf "%q" .ComponentName}}
+	RequestPort   = "request"
+	ResponsePort  = "response"
+	ErrorPort     = "error"
+)
+
+// API method called by the component
+const (
+	Service    = {{printf "%q" .Service}}
+	MethodID   = {{printf "%q" .MethodID}}
+	HTTPMethod = {{printf "%q" .HTTPMethod}}
+	BaseURL    = {{printf "%q" .BaseURL}}
+	Path       = {{printf "%q" .Path}}
+)
+
+// Scopes authorizing the method, any of them is sufficient
+var Scopes = []string{ {{- range $i, $s := .Scopes}}{{if $i}}, {{end}}{{printf "%q" $s}}{{end -}} }
+
+type Context any
+
+type Settings struct {
+	EnableErrorPort bool               ` + "`" + `json:"enableErrorPort" required:"true" title:"Enable Error Port" description:"If request may fail, error port will emit an error message"` + "`" + `
+	RateLimit       ratelimit.Settings ` + "`" + `json:"rateLimit" title:"Rate Limit" description:"Client-side rate limit shared with other components calling the same service"` + "`" + `
+}
+
+type Component struct {
+	settings Settings
+}
+
+type Request struct {
+	Context Context          ` + "`" + `json:"context" title:"Context" configurable:"true"` + "`" + `
+	Config  etc.ClientConfig ` + "`" + `json:"config" title:"Config" required:"true" description:"Client Config"` + "`" + `
+	Token   *etc.Token       ` + "`" + `json:"token,omitempty" title:"Auth Token"` + "`" + `
+{{- range .Params}}
+	{{.Field}} {{.Type}} ` + "`" + `{{.Tags}}` + "`" + `
+{{- end}}
+{{- if .BodyType}}
+	Body {{.BodyType}} ` + "`" + `json:"body" required:"true" title:"Body"` + "`" + `
+{{- end}}
+}
+
+type Response struct {
+	Context Context ` + "`" + `json:"context" title:"Context" configurable:"true"` + "`" + `
+{{- if .ResultType}}
+	Result  {{.ResultType}} ` + "`" + `json:"result" title:"Result"` + "`" + `
+{{- end}}
+}
+
+type Error struct {
+	Context Context ` + "`" + `json:"context"` + "`" + `
+	Error   string  ` + "`" + `json:"error"` + "`" + `
+}
+
+func (g *Component) GetInfo() module.ComponentInfo {
+	return module.ComponentInfo{
+		Name:        ComponentName,
+		Description: {{printf "%q" .Title}},
+		Info:        {{printf "%q" .Info}},
+		Tags:        []string{"google", {{printf "%q" .APIName}}},
+	}
+}
+
+// OnSettings stores the component settings.
+func (g *Component) OnSettings(_ context.Context, msg any) error {
+	in, ok := msg.(Settings)
+	if !ok {
+		return fmt.Errorf("invalid settings")
+	}
+	g.settings = in
+	return nil
+}
+
+// Handle dispatches business ports. System ports go through capabilities.
+func (g *Component) Handle(ctx context.Context, output module.Handler, port string, msg any) module.Result {
+	if port != RequestPort {
+		return module.Fail(fmt.Errorf("unknown port %s", port))
+	}
+
+	in, ok := msg.(Request)
+	if !ok {
+		return module.Fail(fmt.Errorf("invalid input message"))
+	}
+
+	{{if .ResultType}}result, err{{else}}err{{end}} := g.call(ctx, in)
+	if err != nil {
+		// check err port
+		if !g.settings.EnableErrorPort {
+			return module.Fail(err)
+		}
+		return output(ctx, ErrorPort, Error{
+			Context: in.Context,
+			Error:   err.Error(),
+		})
+	}
+
+	return output(ctx, ResponsePort, Response{
+		Context: in.Context,
+{{- if .ResultType}}
+		Result:  result,
+{{- end}}
+	})
+}
+
+func (g *Component) call(ctx context.Context, req Request) ({{if .ResultType}}{{.ResultType}}, {{end}}error) {
+	if err := etc.RateLimit(ctx, Service, req.Config, req.Token, g.settings.RateLimit); err != nil {
+		return {{if .ResultType}}{{.ResultZero}}, {{end}}err
+	}
+
+	if len(req.Config.Scopes) == 0 {
+		req.Config.Scopes = Scopes
+	}
+	client, release, err := etc.SharedGoogleHTTPClient(ctx, req.Config, req.Token)
+	if err != nil {
+		return {{if .ResultType}}{{.ResultZero}}, {{end}}fmt.Errorf("unable to create google client: %v", err)
+	}
+	defer release()
+
+	path := rest.Expand(Path, map[string]string{
+{{- range .PathParams}}
+		{{printf "%q" .Name}}: fmt.Sprint(req.{{.Field}}),
+{{- end}}
+	})
+
+	query := url.Values{}
+{{- range .QueryParams}}
+	rest.AddQuery(query, {{printf "%q" .Name}}, req.{{.Field}})
+{{- end}}
+{{if .ResultType}}
+	var result {{.ResultElem}}
+	err = rest.Do(telemetry.WithCall(ctx, Service, MethodID), client, HTTPMethod, BaseURL+path, query, {{if .BodyType}}req.Body{{else}}nil{{end}}, &result)
+	if err != nil {
+		return {{.ResultZero}}, err
+	}
+	return {{if .ResultPointer}}&result{{else}}result{{end}}, nil
+{{- else}}
+	return rest.Do(telemetry.WithCall(ctx, Service, MethodID), client, HTTPMethod, BaseURL+path, query, {{if .BodyType}}req.Body{{else}}nil{{end}}, nil)
+{{- end}}
+}
+
+func (g *Component) Ports() []module.Port {
+	ports := []module.Port{
+		{
+			Name:          v1alpha1.SettingsPort,
+			Label:         "Settings",
+			Configuration: Settings{},
+		},
+		{
+			Name:          RequestPort,
+			Label:         "Request",
+			Position:      module.Left,
+			Configuration: Request{},
+		},
+		{
+			Source:        true,
+			Name:          ResponsePort,
+			Label:         "Response",
+			Position:      module.Right,
+			Configuration: Response{},
+		},
+	}
+	if !g.settings.EnableErrorPort {
+		return ports
+	}
+
+	return append(ports, module.Port{
+		Position:      module.Bottom,
+		Name:          ErrorPort,
+		Label:         "Error",
+		Source:        true,
+		Configuration: Error{},
+	})
+}
+
+func (g *Component) Instance() module.Component {
+	return &Component{}
+}
+
+var (
+	_ module.Component       = (*Component)(nil)
+	_ module.SettingsHandler = (*Component)(nil)
+)
+
+func init() {
+	registry.Register(&Component{})
+}
+`))
+
+var typesTemplate = template.Must(template.New("types").Parse(`// Code generated by googleapis-module discovery generate. DO NOT EDIT.
+
+package {{.Package}}
+
+{{.Types}}`))
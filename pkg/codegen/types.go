@@ -0,0 +1,295 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// maxDescription limits descriptions copied into struct tags
+const maxDescription = 300
+
+// reservedNames are declared by the component template
+var reservedNames = map[string]bool{
+	"Component":     true,
+	"Context":       true,
+	"Error":         true,
+	"Request":       true,
+	"Response":      true,
+	"Settings":      true,
+	"ComponentName": true,
+	"RequestPort":   true,
+	"ResponsePort":  true,
+	"ErrorPort":     true,
+	"Service":       true,
+	"MethodID":      true,
+	"HTTPMethod":    true,
+	"BaseURL":       true,
+	"Path":          true,
+	"Scopes":        true,
+}
+
+// typeName returns the Go type name of a schema, avoiding names used by the component
+func typeName(ref string) string {
+	name := exportedName(ref)
+	if reservedNames[name] {
+		return name + "Schema"
+	}
+	return name
+}
+
+// typeWriter generates Go types for discovery schemas used by a method
+type typeWriter struct {
+	api *googleapismodule.API
+
+	// generated type declarations by Go type name
+	decls map[string]string
+	// schema refs already generated or in progress, by ref
+	refs map[string]bool
+}
+
+func newTypeWriter(api *googleapismodule.API) *typeWriter {
+	return &typeWriter{
+		api:   api,
+		decls: make(map[string]string),
+		refs:  make(map[string]bool),
+	}
+}
+
+// source returns all declarations sorted by type name
+func (w *typeWriter) source() string {
+	names := make([]string, 0, len(w.decls))
+	for name := range w.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(w.decls[name])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ref returns the Go type of a referenced schema, generating it on first use
+func (w *typeWriter) ref(ref string) string {
+	name := typeName(ref)
+	schema, ok := w.api.Schemas[ref]
+	if !ok {
+		return "any"
+	}
+	if !w.refs[ref] {
+		w.refs[ref] = true
+		if isStruct(schema) {
+			w.structDecl(name, schema)
+		} else {
+			w.decls[name] = fmt.Sprintf("%stype %s %s\n", comment(name, schema.Description), name, w.goType(schema, name))
+		}
+	}
+	if isStruct(schema) {
+		// pointers keep recursive schemas like Status.details compilable
+		return "*" + name
+	}
+	return name
+}
+
+// goType maps a discovery schema to a Go type, nested objects become named types prefixed by parent
+func (w *typeWriter) goType(schema googleapismodule.Schema, parent string) string {
+	if schema.Ref != "" {
+		return w.ref(schema.Ref)
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return integerType(schema.Format)
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if schema.Items == nil {
+			return "[]any"
+		}
+		return "[]" + w.goType(*schema.Items, parent+"Item")
+	case "object":
+		if len(schema.Properties) > 0 {
+			w.structDecl(parent, schema)
+			return "*" + parent
+		}
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + w.goType(*schema.AdditionalProperties, parent+"Value")
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// structDecl generates a struct for an object schema
+func (w *typeWriter) structDecl(name string, schema googleapismodule.Schema) {
+	if _, ok := w.decls[name]; ok {
+		return
+	}
+	// reserve the name before recursing into properties
+	w.decls[name] = ""
+
+	var b strings.Builder
+	b.WriteString(comment(name, schema.Description))
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+
+	used := make(map[string]bool)
+	for _, prop := range sortedKeys(schema.Properties) {
+		propSchema := schema.Properties[prop]
+		field := uniqueName(exportedName(prop), used)
+		typ := w.goType(propSchema, name+field)
+		fmt.Fprintf(&b, "\t%s %s `%s`\n", field, typ, tags(prop, false, propSchema.Description, propSchema.Enum, ""))
+	}
+	b.WriteString("}\n")
+
+	w.decls[name] = b.String()
+}
+
+// paramType maps a method parameter to a Go type
+func paramType(param googleapismodule.Parameter) string {
+	var typ string
+	switch param.Type {
+	case "integer":
+		typ = integerType(param.Format)
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	default:
+		typ = "string"
+	}
+	if param.Repeated {
+		return "[]" + typ
+	}
+	return typ
+}
+
+func integerType(format string) string {
+	switch format {
+	case "int32":
+		return "int32"
+	case "uint32":
+		return "uint32"
+	}
+	return "int64"
+}
+
+func isStruct(schema googleapismodule.Schema) bool {
+	return schema.Type == "object" && len(schema.Properties) > 0
+}
+
+// tags builds struct tags in the style of hand written components
+func tags(name string, required bool, description string, enum []string, def string) string {
+	parts := []string{fmt.Sprintf(`json:"%s,omitempty"`, name)}
+	if required {
+		parts[0] = fmt.Sprintf(`json:"%s"`, name)
+		parts = append(parts, `required:"true"`)
+	}
+	parts = append(parts, "title:"+quoteTag(title(name)))
+	if d := shortDescription(description); d != "" {
+		parts = append(parts, "description:"+quoteTag(d))
+	}
+	if len(enum) > 0 {
+		parts = append(parts, "enum:"+quoteTag(strings.Join(enum, ",")))
+	}
+	if def != "" {
+		parts = append(parts, "default:"+quoteTag(def))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteTag quotes a tag value, backticks can't appear in raw string literals
+func quoteTag(s string) string {
+	return strconv.Quote(strings.ReplaceAll(s, "`", "'"))
+}
+
+// shortDescription keeps the first paragraph of a description
+func shortDescription(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if len(s) > maxDescription {
+		s = strings.TrimSpace(s[:maxDescription-3]) + "..."
+	}
+	return s
+}
+
+func comment(name, description string) string {
+	if d := shortDescription(description); d != "" {
+		return "// " + name + " " + lowerFirst(d) + "\n"
+	}
+	return ""
+}
+
+// exportedName turns discovery names like calendarId or $.xgafv into Go identifiers
+func exportedName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+// title turns calendarId into "Calendar Id"
+func title(s string) string {
+	name := exportedName(s)
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(rune(name[i-1])) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsUpper(r[1]) {
+		// acronyms like "ETag of the resource" stay as is
+		return s
+	}
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
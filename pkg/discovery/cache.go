@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// CacheDirEnv is the environment variable with the default on-disk cache directory
+const CacheDirEnv = "DISCOVERY_CACHE_DIR"
+
+// ListURLEnv is the environment variable overriding the discovery directory URL,
+// e.g. to point components at a fake server
+const ListURLEnv = "DISCOVERY_LIST_URL"
+
+const listFile = "list.json"
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithCacheDir stores fetched discovery documents in dir and reads them
+// back when Google can't be reached
+func WithCacheDir(dir string) Option {
+	return func(c *Client) {
+		c.cacheDir = dir
+	}
+}
+
+// WithOffline serves documents from the cache directory only, without network access
+func WithOffline(offline bool) Option {
+	return func(c *Client) {
+		c.offline = offline
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to fetch discovery documents
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithListURL overrides the discovery directory URL
+func WithListURL(url string) Option {
+	return func(c *Client) {
+		c.listURL = url
+	}
+}
+
+// apiFile returns the cache file name of a service, e.g. calendar.v3.json for calendar:v3
+func apiFile(serviceID string) string {
+	return strings.ReplaceAll(serviceID, ":", ".") + ".json"
+}
+
+// readCache reads a cached document into v
+func (c *Client) readCache(name string, v any) error {
+	if c.cacheDir == "" {
+		return fmt.Errorf("no discovery cache directory configured")
+	}
+	data, err := os.ReadFile(filepath.Join(c.cacheDir, name))
+	if err != nil {
+		return fmt.Errorf("discovery cache: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("discovery cache %s is corrupted: %w", name, err)
+	}
+	return nil
+}
+
+// writeCache atomically stores a raw document, it's a no-op without cache directory
+func (c *Client) writeCache(name string, data []byte) error {
+	if c.cacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create discovery cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.cacheDir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write discovery cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write discovery cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write discovery cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.cacheDir, name))
+}
+
+// embeddedList returns the discovery directory snapshot shipped with the module
+func embeddedList() (*googleapismodule.Discovery, error) {
+	var discovery googleapismodule.Discovery
+	if err := json.Unmarshal(googleapismodule.DiscoveryList, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to decode embedded discovery list: %w", err)
+	}
+	return &discovery, nil
+}
@@ -2,15 +2,19 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
 	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
 )
 
 const (
@@ -18,6 +22,9 @@ const (
 	DiscoveryListURL = "https://discovery.googleapis.com/discovery/v1/apis"
 )
 
+// ErrOffline is returned when documents are refreshed by a client without network access
+var ErrOffline = errors.New("discovery documents can't be refreshed offline")
+
 // Client provides access to Google API Discovery documents
 type Client struct {
 	httpClient *http.Client
@@ -33,17 +40,35 @@ type Client struct {
 
 	// Cache TTL
 	cacheTTL time.Duration
+
+	listURL string
+
+	// On-disk cache used as fallback, or as the only source when offline
+	cacheDir string
+	offline  bool
 }
 
-// NewClient creates a new Discovery client
-func NewClient() *Client {
-	return &Client{
+// NewClient creates a new Discovery client.
+// The on-disk cache defaults to the DISCOVERY_CACHE_DIR environment variable,
+// the directory URL may be overridden with DISCOVERY_LIST_URL.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: cassette.NewTransport(http.DefaultTransport),
 		},
 		apiCache: make(map[string]*googleapismodule.API),
 		cacheTTL: 1 * time.Hour, // Cache for 1 hour
+		listURL:  DiscoveryListURL,
+		cacheDir: os.Getenv(CacheDirEnv),
+	}
+	if listURL := os.Getenv(ListURLEnv); listURL != "" {
+		c.listURL = listURL
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // ServiceOption represents a service available in the discovery list
@@ -110,6 +135,28 @@ func (c *Client) GetAPI(ctx context.Context, serviceID string) (*googleapismodul
 	}
 	c.apiCacheMu.RUnlock()
 
+	if c.offline {
+		return c.cachedAPI(serviceID)
+	}
+
+	api, err := c.RefreshAPI(ctx, serviceID)
+	if err != nil {
+		// Fall back to a previously downloaded spec
+		if cached, cacheErr := c.cachedAPI(serviceID); cacheErr == nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return api, nil
+}
+
+// RefreshAPI downloads the API specification bypassing all caches,
+// the result is stored in memory and on disk
+func (c *Client) RefreshAPI(ctx context.Context, serviceID string) (*googleapismodule.API, error) {
+	if c.offline {
+		return nil, fmt.Errorf("unable to refresh API spec for %s: %w", serviceID, ErrOffline)
+	}
+
 	// Get discovery URL for this service
 	discoveryURL, err := c.getDiscoveryURL(ctx, serviceID)
 	if err != nil {
@@ -117,17 +164,40 @@ func (c *Client) GetAPI(ctx context.Context, serviceID string) (*googleapismodul
 	}
 
 	// Fetch the API spec
-	api, err := c.fetchAPI(ctx, discoveryURL)
+	data, err := c.fetch(ctx, discoveryURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch API spec for %s: %w", serviceID, err)
 	}
 
+	var api googleapismodule.API
+	if err := json.Unmarshal(data, &api); err != nil {
+		return nil, fmt.Errorf("failed to decode API spec for %s: %w", serviceID, err)
+	}
+	if err := c.writeCache(apiFile(serviceID), data); err != nil {
+		// the fetched spec is still good, it just won't survive a restart
+		log.Warn().Err(err).Str("service", serviceID).Msg("failed to cache API spec")
+	}
+
 	// Cache it
 	c.apiCacheMu.Lock()
-	c.apiCache[serviceID] = api
+	c.apiCache[serviceID] = &api
 	c.apiCacheMu.Unlock()
 
-	return api, nil
+	return &api, nil
+}
+
+// cachedAPI reads an API specification from the on-disk cache
+func (c *Client) cachedAPI(serviceID string) (*googleapismodule.API, error) {
+	var api googleapismodule.API
+	if err := c.readCache(apiFile(serviceID), &api); err != nil {
+		return nil, fmt.Errorf("API spec for %s is not available offline: %w", serviceID, err)
+	}
+
+	c.apiCacheMu.Lock()
+	c.apiCache[serviceID] = &api
+	c.apiCacheMu.Unlock()
+
+	return &api, nil
 }
 
 // GetMethods returns all available methods for a given service
@@ -165,30 +235,67 @@ func (c *Client) getDiscoveryList(ctx context.Context) (*googleapismodule.Discov
 		return c.discoveryListCache, nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, DiscoveryListURL, nil)
+	discovery, err := c.loadDiscoveryList(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	c.discoveryListCache = discovery
+	c.discoveryListCacheTime = time.Now()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("discovery list request failed with status %d: %s", resp.StatusCode, string(body))
+	return discovery, nil
+}
+
+// loadDiscoveryList fetches the discovery list, falling back to
+// the on-disk cache and then to the snapshot embedded in the module
+func (c *Client) loadDiscoveryList(ctx context.Context) (*googleapismodule.Discovery, error) {
+	if !c.offline {
+		if discovery, err := c.fetchDiscoveryList(ctx); err == nil {
+			return discovery, nil
+		}
 	}
 
 	var discovery googleapismodule.Discovery
-	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
-		return nil, fmt.Errorf("failed to decode discovery list: %w", err)
+	if err := c.readCache(listFile, &discovery); err == nil {
+		return &discovery, nil
+	}
+	return embeddedList()
+}
+
+// RefreshList downloads the discovery list bypassing all caches,
+// the result is stored in memory and on disk
+func (c *Client) RefreshList(ctx context.Context) error {
+	if c.offline {
+		return fmt.Errorf("unable to refresh discovery list: %w", ErrOffline)
+	}
+
+	discovery, err := c.fetchDiscoveryList(ctx)
+	if err != nil {
+		return err
 	}
 
-	c.discoveryListCache = &discovery
+	c.discoveryListMu.Lock()
+	c.discoveryListCache = discovery
 	c.discoveryListCacheTime = time.Now()
+	c.discoveryListMu.Unlock()
+
+	return nil
+}
+
+// fetchDiscoveryList downloads the discovery list and stores it on disk
+func (c *Client) fetchDiscoveryList(ctx context.Context) (*googleapismodule.Discovery, error) {
+	data, err := c.fetch(ctx, c.listURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery list: %w", err)
+	}
 
+	var discovery googleapismodule.Discovery
+	if err := json.Unmarshal(data, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery list: %w", err)
+	}
+	if err := c.writeCache(listFile, data); err != nil {
+		log.Warn().Err(err).Msg("failed to cache discovery list")
+	}
 	return &discovery, nil
 }
 
@@ -208,8 +315,8 @@ func (c *Client) getDiscoveryURL(ctx context.Context, serviceID string) (string,
 	return "", fmt.Errorf("service %s not found in discovery list", serviceID)
 }
 
-// fetchAPI fetches an API spec from a discovery URL
-func (c *Client) fetchAPI(ctx context.Context, url string) (*googleapismodule.API, error) {
+// fetch downloads a discovery document
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -223,15 +330,10 @@ func (c *Client) fetchAPI(ctx context.Context, url string) (*googleapismodule.AP
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API spec request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("discovery request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var api googleapismodule.API
-	if err := json.NewDecoder(resp.Body).Decode(&api); err != nil {
-		return nil, fmt.Errorf("failed to decode API spec: %w", err)
-	}
-
-	return &api, nil
+	return io.ReadAll(resp.Body)
 }
 
 // ClearCache clears all cached data
@@ -0,0 +1,70 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOffline(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = fmt.Fprintf(w, `{"items":[{"id":"calendar:v3","name":"calendar","version":"v3","discoveryRestUrl":"%s/calendar"}]}`, server.URL)
+	})
+	mux.HandleFunc("/calendar", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"id":"calendar:v3","name":"calendar","version":"v3"}`))
+	})
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	online := NewClient(WithCacheDir(dir), WithListURL(server.URL+"/apis"))
+	if err := online.RefreshList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := online.RefreshAPI(ctx, "calendar:v3"); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched := requests.Load()
+	offline := NewClient(WithCacheDir(dir), WithListURL(server.URL+"/apis"), WithOffline(true))
+
+	if err := offline.RefreshList(ctx); !errors.Is(err, ErrOffline) {
+		t.Fatalf("RefreshList: expected ErrOffline, got %v", err)
+	}
+	if _, err := offline.RefreshAPI(ctx, "calendar:v3"); !errors.Is(err, ErrOffline) {
+		t.Fatalf("RefreshAPI: expected ErrOffline, got %v", err)
+	}
+
+	api, err := offline.GetAPI(ctx, "calendar:v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Name != "calendar" {
+		t.Fatalf("unexpected API %+v", api)
+	}
+	services, err := offline.GetServices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].ID != "calendar:v3" {
+		t.Fatalf("services %+v, want the cached list", services)
+	}
+	if _, err := offline.GetAPI(ctx, "sheets:v4"); err == nil {
+		t.Fatal("expected an error for a document that isn't cached")
+	}
+
+	if n := requests.Load(); n != fetched {
+		t.Fatalf("offline client made %d requests", n-fetched)
+	}
+}
@@ -0,0 +1,109 @@
+package fakeserver
+
+import (
+	"net/http"
+)
+
+// Call is a request received by a fake endpoint
+type Call struct {
+	// MethodID is the discovery method ID, e.g. calendar.events.list
+	MethodID   string
+	HTTPMethod string
+	// Path is relative to the service path, e.g. calendars/primary/events
+	Path string
+	// Params holds path and query parameters
+	Params map[string]any
+	Body   map[string]any
+	Header http.Header
+	// Status is the status code answered
+	Status int
+	// Err is set when the request failed validation
+	Err error
+}
+
+// Fixture is a scripted response of a method
+type Fixture struct {
+	// Status defaults to 200
+	Status int               `json:"status,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is encoded as JSON, nil answers with an empty body
+	Body any `json:"body,omitempty"`
+}
+
+func (f Fixture) status() int {
+	if f.Status == 0 {
+		return http.StatusOK
+	}
+	return f.Status
+}
+
+// Stub scripts responses of a method by its ID, e.g. calendar.events.list.
+// Fixtures are answered in order and the last one repeats, so a single fixture answers every call.
+// Stubbing a method again replaces its fixtures.
+func (s *Server) Stub(methodID string, fixtures ...Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(fixtures) == 0 {
+		delete(s.fixtures, methodID)
+		return
+	}
+	s.fixtures[methodID] = fixtures
+}
+
+// Calls returns all recorded calls in order
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo returns recorded calls of a method
+func (s *Server) CallsTo(methodID string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, c := range s.calls {
+		if c.MethodID == methodID {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset forgets recorded calls and fixtures
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+	s.fixtures = make(map[string][]Fixture)
+}
+
+func (s *Server) record(call Call) {
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	onCall := s.OnCall
+	s.mu.Unlock()
+
+	if onCall != nil {
+		onCall(call)
+	}
+}
+
+// nextFixture pops the next scripted response, keeping the last one
+func (s *Server) nextFixture(methodID string) (Fixture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fixtures := s.fixtures[methodID]
+	if len(fixtures) == 0 {
+		return Fixture{}, false
+	}
+	if len(fixtures) > 1 {
+		s.fixtures[methodID] = fixtures[1:]
+	}
+	return fixtures[0], true
+}
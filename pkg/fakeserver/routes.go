@@ -0,0 +1,120 @@
+package fakeserver
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// placeholder matches {name} and reserved {+name} path template expressions
+var placeholder = regexp.MustCompile(`\{(\+?)([^}]+)\}`)
+
+// template is a compiled discovery path template
+type template struct {
+	re    *regexp.Regexp
+	names []string
+	// literal is the number of non-placeholder characters, more literal templates win
+	literal int
+}
+
+// route is a fake endpoint of a discovery method, methods with a flat path get
+// a route for each template since the flat one covers only a single resource type
+type route struct {
+	method googleapismodule.Method
+	// match routes requests
+	match template
+	// params extracts parameters named like in the method definition
+	params template
+}
+
+func newRoutes(api *googleapismodule.API) []route {
+	var routes []route
+	for _, m := range api.GetAllMethods() {
+		params := compile(m.Method.Path)
+		routes = append(routes, route{method: m.Method, match: params, params: params})
+		if m.Method.FlatPath != "" && m.Method.FlatPath != m.Method.Path {
+			routes = append(routes, route{method: m.Method, match: compile(m.Method.FlatPath), params: params})
+		}
+	}
+
+	// most specific templates first, so v1/{+parent}/documents wins over v1/{+name}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].match.literal != routes[j].match.literal {
+			return routes[i].match.literal > routes[j].match.literal
+		}
+		return routes[i].method.ID < routes[j].method.ID
+	})
+	return routes
+}
+
+// compile turns a path template like calendars/{calendarId}/events into a regexp
+func compile(path string) template {
+	t := template{literal: len(placeholder.ReplaceAllString(path, ""))}
+
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(path, -1) {
+		b.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		if loc[3] > loc[2] {
+			// reserved expansion may contain slashes
+			b.WriteString("(.+)")
+		} else {
+			b.WriteString("([^/]+)")
+		}
+		t.names = append(t.names, path[loc[4]:loc[5]])
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(path[last:]))
+	b.WriteString("$")
+
+	t.re = regexp.MustCompile(b.String())
+	return t
+}
+
+// extract returns unescaped template values of an escaped path
+func (t template) extract(escapedPath string) (map[string]string, bool) {
+	m := t.re.FindStringSubmatch(escapedPath)
+	if m == nil {
+		return nil, false
+	}
+	values := make(map[string]string, len(t.names))
+	for i, name := range t.names {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			v = m[i+1]
+		}
+		values[name] = v
+	}
+	return values, true
+}
+
+// match finds the route of a request and its path parameters
+func match(routes []route, httpMethod, escapedPath string) (route, map[string]string, bool) {
+	for _, rt := range routes {
+		if rt.method.HttpMethod != httpMethod {
+			continue
+		}
+		values, ok := rt.match.extract(escapedPath)
+		if !ok {
+			continue
+		}
+		if named, ok := rt.params.extract(escapedPath); ok {
+			values = named
+		}
+		return rt, values, true
+	}
+	return route{}, nil, false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
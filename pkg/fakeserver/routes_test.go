@@ -0,0 +1,210 @@
+package fakeserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+// routingAPI mimics the path templates of Firestore and Calendar
+const routingAPI = `{
+	"id": "test:v1",
+	"name": "test",
+	"version": "v1",
+	"servicePath": "",
+	"schemas": {
+		"Result": {"id": "Result", "type": "object", "properties": {"id": {"type": "string"}}}
+	},
+	"resources": {
+		"documents": {
+			"methods": {
+				"get": {
+					"id": "test.documents.get",
+					"response": {"$ref": "Result"},
+					"path": "v1/{+name}",
+					"flatPath": "v1/projects/{projectsId}/databases/{databasesId}/documents/{documentsId}/{documentsId1}",
+					"httpMethod": "GET",
+					"parameters": {"name": {"type": "string", "location": "path", "required": true, "pattern": "^projects/[^/]+/databases/[^/]+/documents/[^/]+/.*$"}}
+				},
+				"list": {
+					"id": "test.documents.list",
+					"response": {"$ref": "Result"},
+					"path": "v1/{+parent}/{collectionId}",
+					"flatPath": "v1/projects/{projectsId}/databases/{databasesId}/documents/{documentsId}/{collectionId}",
+					"httpMethod": "GET",
+					"parameters": {
+						"parent": {"type": "string", "location": "path", "required": true},
+						"collectionId": {"type": "string", "location": "path", "required": true},
+						"pageSize": {"type": "integer", "location": "query"}
+					}
+				},
+				"runQuery": {
+					"id": "test.documents.runQuery",
+					"response": {"$ref": "Result"},
+					"path": "v1/{+parent}:runQuery",
+					"httpMethod": "POST",
+					"parameters": {"parent": {"type": "string", "location": "path", "required": true}}
+				}
+			}
+		},
+		"events": {
+			"methods": {
+				"list": {
+					"id": "test.events.list",
+					"response": {"$ref": "Result"},
+					"path": "calendars/{calendarId}/events",
+					"httpMethod": "GET",
+					"parameters": {
+						"calendarId": {"type": "string", "location": "path", "required": true},
+						"eventTypes": {"type": "string", "location": "query", "repeated": true}
+					}
+				},
+				"delete": {
+					"id": "test.events.delete",
+					"path": "calendars/{calendarId}/events/{eventId}",
+					"httpMethod": "DELETE",
+					"parameters": {
+						"calendarId": {"type": "string", "location": "path", "required": true},
+						"eventId": {"type": "string", "location": "path", "required": true}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func TestRouting(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(routingAPI), &api); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(&api)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		id     string
+		params map[string]any
+	}{
+		{
+			name:   "reserved expansion keeps slashes",
+			method: http.MethodGet,
+			path:   "v1/projects/p/databases/(default)/documents/users/alice",
+			status: http.StatusOK,
+			id:     "test.documents.get",
+			params: map[string]any{"name": "projects/p/databases/(default)/documents/users/alice"},
+		},
+		{
+			name:   "more literal template wins",
+			method: http.MethodPost,
+			path:   "v1/projects/p/databases/(default)/documents:runQuery",
+			status: http.StatusOK,
+			id:     "test.documents.runQuery",
+			params: map[string]any{"parent": "projects/p/databases/(default)/documents"},
+		},
+		{
+			name:   "flat path match is named like the method path",
+			method: http.MethodGet,
+			path:   "v1/projects/p/databases/(default)/documents/users?pageSize=10",
+			status: http.StatusOK,
+			id:     "test.documents.list",
+			params: map[string]any{"parent": "projects/p/databases/(default)/documents", "collectionId": "users", "pageSize": "10"},
+		},
+		{
+			name:   "escaped slash stays in one segment",
+			method: http.MethodGet,
+			path:   "calendars/team%2Fshared@group.calendar.google.com/events",
+			status: http.StatusOK,
+			id:     "test.events.list",
+			params: map[string]any{"calendarId": "team/shared@group.calendar.google.com"},
+		},
+		{
+			name:   "repeated query parameter",
+			method: http.MethodGet,
+			path:   "calendars/primary/events?eventTypes=default&eventTypes=focusTime",
+			status: http.StatusOK,
+			id:     "test.events.list",
+			params: map[string]any{"calendarId": "primary", "eventTypes": []any{"default", "focusTime"}},
+		},
+		{
+			name:   "http method selects the route, methods without response answer 204",
+			method: http.MethodDelete,
+			path:   "calendars/primary/events/e1",
+			status: http.StatusNoContent,
+			id:     "test.events.delete",
+			params: map[string]any{"calendarId": "primary", "eventId": "e1"},
+		},
+		{name: "unknown path", method: http.MethodGet, path: "calendars/primary", status: http.StatusNotFound},
+		{name: "unknown http method", method: http.MethodPut, path: "calendars/primary/events", status: http.StatusNotFound},
+		{name: "unknown API", method: http.MethodGet, path: "../other/v1/calendars/primary/events", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.Reset()
+
+			req, err := http.NewRequest(tt.method, ts.URL+apisPath+"test/v1/"+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.id == "" {
+				if calls := srv.Calls(); len(calls) != 0 {
+					t.Fatalf("unexpected calls %+v", calls)
+				}
+				return
+			}
+
+			calls := srv.CallsTo(tt.id)
+			if len(calls) != 1 {
+				t.Fatalf("calls to %s: %+v", tt.id, srv.Calls())
+			}
+			for k, want := range tt.params {
+				got, _ := json.Marshal(calls[0].Params[k])
+				expected, _ := json.Marshal(want)
+				if string(got) != string(expected) {
+					t.Errorf("param %s = %s, want %s", k, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutingServesDiscovery(t *testing.T) {
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(routingAPI), &api); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(New(&api))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + ListPath + "/test/v1/rest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var doc googleapismodule.API
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.BaseUrl, ts.URL+apisPath+"test/v1/") {
+		t.Fatalf("base URL %s doesn't point to the fake server", doc.BaseUrl)
+	}
+}
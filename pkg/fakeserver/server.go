@@ -0,0 +1,268 @@
+// Package fakeserver is a local stand-in for Google REST APIs built from discovery documents.
+// It serves the documents themselves, so google_api_call can be pointed at it, and fake
+// endpoints which validate requests and answer with schema-shaped examples or scripted fixtures.
+package fakeserver
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+	"github.com/tiny-systems/googleapis-module/pkg/apischema"
+)
+
+const (
+	// ListPath serves the discovery directory, use the server URL plus ListPath
+	// as discovery list URL
+	ListPath = "/discovery/v1/apis"
+
+	// apisPath prefixes fake endpoints of each API, followed by name/version/
+	apisPath = "/apis/"
+)
+
+// Server serves discovery documents and fake endpoints of the given APIs.
+// It's safe for concurrent use.
+type Server struct {
+	apis   map[string]*googleapismodule.API // by name/version
+	routes map[string][]route               // by name/version
+
+	// OnCall is called after every recorded call, e.g. to log traffic
+	OnCall func(Call)
+
+	mu       sync.Mutex
+	calls    []Call
+	fixtures map[string][]Fixture // by method ID
+}
+
+// New creates a server for the given discovery documents
+func New(apis ...*googleapismodule.API) *Server {
+	s := &Server{
+		apis:     make(map[string]*googleapismodule.API),
+		routes:   make(map[string][]route),
+		fixtures: make(map[string][]Fixture),
+	}
+	for _, api := range apis {
+		key := api.Name + "/" + api.Version
+		s.apis[key] = api
+		s.routes[key] = newRoutes(api)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == ListPath:
+		s.serveList(w, r)
+	case strings.HasPrefix(r.URL.Path, ListPath+"/"):
+		s.serveAPI(w, r)
+	case strings.HasPrefix(r.URL.Path, apisPath):
+		s.serveMethod(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "notFound", "unknown path "+r.URL.Path)
+	}
+}
+
+// serveList answers with a discovery directory pointing back to this server
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request) {
+	base := baseURL(r)
+
+	list := googleapismodule.Discovery{
+		Kind:             "discovery#directoryList",
+		DiscoveryVersion: "v1",
+	}
+	for _, key := range sortedKeys(s.apis) {
+		api := s.apis[key]
+		list.Items = append(list.Items, googleapismodule.DiscoveryItem{
+			Kind:             "discovery#directoryItem",
+			ID:               api.ID,
+			Name:             api.Name,
+			Version:          api.Version,
+			Title:            api.Title,
+			Description:      api.Description,
+			DiscoveryRestUrl: base + ListPath + "/" + key + "/rest",
+			Preferred:        true,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// serveAPI answers with a discovery document whose endpoints are rewritten to this server
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, ListPath+"/"), "/rest")
+	api, found := s.apis[key]
+	if !ok || !found {
+		writeError(w, http.StatusNotFound, "notFound", "unknown API "+key)
+		return
+	}
+
+	doc := *api
+	doc.RootUrl = baseURL(r) + apisPath + key + "/"
+	doc.BaseUrl = doc.RootUrl + api.ServicePath
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// serveMethod routes a fake API call, validates it and answers with a fixture or an example
+func (s *Server) serveMethod(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, apisPath)
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 {
+		writeError(w, http.StatusNotFound, "notFound", "unknown path "+r.URL.Path)
+		return
+	}
+	key := parts[0] + "/" + parts[1]
+	api, ok := s.apis[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "notFound", "unknown API "+key)
+		return
+	}
+
+	path, ok := strings.CutPrefix(parts[2], api.ServicePath)
+	if !ok {
+		writeError(w, http.StatusNotFound, "notFound", "unknown path "+r.URL.Path)
+		return
+	}
+
+	// match the escaped path, so escaped slashes stay inside a single segment
+	escaped, _ := strings.CutPrefix(r.URL.EscapedPath(), apisPath+key+"/"+api.ServicePath)
+	rt, pathParams, ok := match(s.routes[key], r.Method, escaped)
+	if !ok {
+		writeError(w, http.StatusNotFound, "notFound", "no method matches "+r.Method+" "+path)
+		return
+	}
+
+	call := Call{
+		MethodID:   rt.method.ID,
+		HTTPMethod: r.Method,
+		Path:       path,
+		Params:     params(api, rt.method, pathParams, r.URL.Query()),
+		Header:     r.Header.Clone(),
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badRequest", "unable to read body: "+err.Error())
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &call.Body); err != nil {
+			call.Status = http.StatusBadRequest
+			s.record(call)
+			writeError(w, http.StatusBadRequest, "parseError", "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	data := make(map[string]any, len(call.Params)+len(call.Body))
+	for k, v := range call.Body {
+		data[k] = v
+	}
+	for k, v := range call.Params {
+		data[k] = v
+	}
+	if err := apischema.ValidateParameters(api, rt.method, data); err != nil {
+		call.Status = http.StatusBadRequest
+		call.Err = err
+		s.record(call)
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	fixture, ok := s.nextFixture(rt.method.ID)
+	if !ok {
+		fixture = example(api, rt.method)
+	}
+	call.Status = fixture.status()
+	s.record(call)
+
+	for k, v := range fixture.Header {
+		w.Header().Set(k, v)
+	}
+	if fixture.Body == nil {
+		w.WriteHeader(call.Status)
+		return
+	}
+	writeJSON(w, call.Status, fixture.Body)
+}
+
+// params collects path and query parameters, query values of repeated parameters are kept as arrays
+func params(api *googleapismodule.API, method googleapismodule.Method, pathParams map[string]string, query url.Values) map[string]any {
+	result := make(map[string]any, len(pathParams)+len(query))
+	for k, v := range pathParams {
+		result[k] = v
+	}
+	for k, values := range query {
+		param, ok := method.Parameters[k]
+		if !ok {
+			param = api.Parameters[k]
+		}
+		if len(values) == 1 && !param.Repeated {
+			result[k] = values[0]
+			continue
+		}
+		list := make([]any, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		result[k] = list
+	}
+	return result
+}
+
+// example builds a schema-shaped response, methods without response answer 204
+func example(api *googleapismodule.API, method googleapismodule.Method) Fixture {
+	if method.Response == nil || method.Response.Ref == "" {
+		return Fixture{Status: http.StatusNoContent}
+	}
+	body := apischema.NewExampleGenerator(api).Schema(googleapismodule.Schema{Ref: method.Response.Ref})
+	if obj, ok := body.(map[string]any); ok {
+		// a generated token would make paginating clients loop forever
+		delete(obj, "nextPageToken")
+	}
+	return Fixture{Status: http.StatusOK, Body: body}
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers in the format of Google API errors
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"status":  errorStatus(status),
+			"errors": []map[string]any{{
+				"domain":  "global",
+				"reason":  reason,
+				"message": message,
+			}},
+		},
+	})
+}
+
+func errorStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	}
+	return "UNKNOWN"
+}
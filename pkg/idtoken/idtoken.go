@@ -0,0 +1,226 @@
+// Package idtoken verifies Google-issued OpenID Connect ID tokens, e.g. from sign-in,
+// Pub/Sub push subscriptions, Cloud Tasks or IAP, against the issuer's JSON Web Key Set.
+package idtoken
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	// GoogleJWKSURL serves the keys signing Google ID tokens
+	GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+	// IAPJWKSURL serves the keys signing Identity-Aware Proxy assertions
+	IAPJWKSURL = "https://www.gstatic.com/iap/verify/public_key-jwk"
+
+	// DefaultLeeway tolerates clock skew between Google and us
+	DefaultLeeway = time.Minute
+)
+
+// GoogleIssuers are the issuers of Google ID tokens
+var GoogleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// ErrInvalidToken is returned for tokens failing any check
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Options of a verification
+type Options struct {
+	// Audiences accepted in aud, e.g. the OAuth client ID or the push endpoint URL. Required.
+	Audiences []string
+	// Issuers accepted in iss, GoogleIssuers if empty
+	Issuers []string
+	// JWKSURL serves the signing keys, GoogleJWKSURL if empty
+	JWKSURL string
+	// HostedDomain is the Google Workspace domain required in hd, not checked if empty
+	HostedDomain string
+	// RequireVerifiedEmail rejects tokens without email_verified
+	RequireVerifiedEmail bool
+	// Leeway is the clock skew tolerated for exp, iat and nbf, DefaultLeeway if zero
+	Leeway time.Duration
+}
+
+// Claims are the verified claims of a token
+type Claims map[string]any
+
+// String returns a string claim
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Bool returns a boolean claim, Google sends some as strings
+func (c Claims) Bool(name string) bool {
+	switch v := c[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Time returns a NumericDate claim
+func (c Claims) Time(name string) time.Time {
+	if v, ok := c[name].(float64); ok {
+		return time.Unix(int64(v), 0).UTC()
+	}
+	return time.Time{}
+}
+
+// Audience returns aud, which may be a string or an array
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		aud := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+	return nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks signature, audience, issuer, expiry and the optional hd and email_verified
+// requirements of a token and returns its claims. Only RS256 and ES256 signatures are accepted.
+func Verify(ctx context.Context, token string, opts Options) (Claims, error) {
+	if len(opts.Audiences) == 0 {
+		return nil, fmt.Errorf("audience is required")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	jwksURL := opts.JWKSURL
+	if jwksURL == "" {
+		jwksURL = GoogleJWKSURL
+	}
+	key, err := publicKey(ctx, jwksURL, h.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := checkClaims(claims, opts, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func checkClaims(claims Claims, opts Options, now time.Time) error {
+	leeway := opts.Leeway
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+
+	issuers := opts.Issuers
+	if len(issuers) == 0 {
+		issuers = GoogleIssuers
+	}
+	if !slices.Contains(issuers, claims.String("iss")) {
+		return fmt.Errorf("issuer %q is not accepted", claims.String("iss"))
+	}
+
+	if !slices.ContainsFunc(claims.Audience(), func(aud string) bool {
+		return slices.Contains(opts.Audiences, aud)
+	}) {
+		return fmt.Errorf("audience %v is not accepted", claims.Audience())
+	}
+
+	exp := claims.Time("exp")
+	if exp.IsZero() {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(exp.Add(leeway)) {
+		return fmt.Errorf("token expired at %s", exp.Format(time.RFC3339))
+	}
+	if iat := claims.Time("iat"); !iat.IsZero() && iat.After(now.Add(leeway)) {
+		return fmt.Errorf("token issued in the future")
+	}
+	if nbf := claims.Time("nbf"); !nbf.IsZero() && nbf.After(now.Add(leeway)) {
+		return fmt.Errorf("token not valid yet")
+	}
+
+	if opts.HostedDomain != "" && claims.String("hd") != opts.HostedDomain {
+		return fmt.Errorf("hosted domain %q is not accepted", claims.String("hd"))
+	}
+	if opts.RequireVerifiedEmail && !claims.Bool("email_verified") {
+		return fmt.Errorf("email is not verified")
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature); err != nil {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		// JWS signatures are r and s concatenated
+		if len(signature) != 64 {
+			return fmt.Errorf("bad signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
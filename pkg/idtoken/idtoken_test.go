@@ -0,0 +1,228 @@
+package idtoken
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var b64 = base64.RawURLEncoding
+
+// signToken returns a JWT signed with an RSA or P-256 key
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + b64.EncodeToString(signature)
+}
+
+// newJWKSServer serves the public keys as a JWKS and counts fetches
+func newJWKSServer(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{
+				"kid": "rsa", "kty": "RSA", "alg": "RS256",
+				"n": b64.EncodeToString(rsaKey.N.Bytes()),
+				"e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kid": "ec", "kty": "EC", "crv": "P-256",
+				"x": b64.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+				"y": b64.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetches
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	srv, fetches := newJWKSServer(t, rsaKey, ecKey)
+
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":            "https://accounts.google.com",
+			"aud":            "client-id",
+			"sub":            "1234",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"hd":             "example.com",
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	opts := Options{
+		Audiences:            []string{"client-id"},
+		JWKSURL:              srv.URL,
+		HostedDomain:         "example.com",
+		RequireVerifiedEmail: true,
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		opts  *Options
+		err   bool
+	}{
+		{name: "rs256", token: signToken(t, "RS256", "rsa", rsaKey, claims(nil))},
+		{name: "es256", token: signToken(t, "ES256", "ec", ecKey, claims(nil))},
+		{name: "issuer without scheme", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"iss": "accounts.google.com"}))},
+		{name: "audience array", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"aud": []string{"other", "client-id"}}))},
+		{name: "email_verified as string", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"email_verified": "true"}))},
+		{name: "expired within leeway", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))},
+		{
+			name:  "custom issuer",
+			token: signToken(t, "ES256", "ec", ecKey, claims(map[string]any{"iss": "https://cloud.google.com/iap", "aud": "/projects/1/global/backendServices/2"})),
+			opts:  &Options{Audiences: []string{"/projects/1/global/backendServices/2"}, Issuers: []string{"https://cloud.google.com/iap"}, JWKSURL: srv.URL},
+		},
+
+		{name: "other signing key", token: signToken(t, "RS256", "rsa", otherKey, claims(nil)), err: true},
+		{name: "algorithm of another key", token: signToken(t, "ES256", "rsa", ecKey, claims(nil)), err: true},
+		{name: "unknown key", token: signToken(t, "RS256", "missing", rsaKey, claims(nil)), err: true},
+		{name: "alg none", token: b64.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + b64.EncodeToString([]byte(`{}`)) + ".", err: true},
+		{name: "malformed", token: "not-a-jwt", err: true},
+		{name: "wrong audience", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"aud": "other"})), err: true},
+		{name: "missing audience", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"aud": nil})), err: true},
+		{name: "wrong issuer", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"iss": "https://evil.example.com"})), err: true},
+		{name: "expired", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), err: true},
+		{name: "no expiry", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"exp": nil})), err: true},
+		{name: "issued in the future", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"iat": now.Add(time.Hour).Unix()})), err: true},
+		{name: "not valid yet", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), err: true},
+		{name: "other hosted domain", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"hd": "other.com"})), err: true},
+		{name: "no hosted domain", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"hd": nil})), err: true},
+		{name: "email not verified", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"email_verified": false})), err: true},
+		{name: "email_verified missing", token: signToken(t, "RS256", "rsa", rsaKey, claims(map[string]any{"email_verified": nil})), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := opts
+			if tt.opts != nil {
+				o = *tt.opts
+			}
+			got, err := Verify(context.Background(), tt.token, o)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String("sub") != "1234" {
+				t.Fatalf("unexpected claims %v", got)
+			}
+		})
+	}
+
+	// unknown key IDs don't refetch within minRefetchInterval
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("keys fetched %d times, want 1", n)
+	}
+}
+
+func TestVerifyRequiresAudience(t *testing.T) {
+	if _, err := Verify(context.Background(), "a.b.c", Options{}); err == nil {
+		t.Fatal("expected an error without audience")
+	}
+}
+
+func TestVerifyFetchesKeysOnce(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	srv, fetches := newJWKSServer(t, rsaKey, ecKey)
+
+	token := signToken(t, "RS256", "rsa", rsaKey, map[string]any{
+		"iss": "accounts.google.com",
+		"aud": "client-id",
+		"sub": "1234",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Verify(context.Background(), token, Options{Audiences: []string{"client-id"}, JWKSURL: srv.URL}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("keys fetched %d times, want 1", n)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "public, max-age=19204, must-revalidate, no-transform", want: 19204 * time.Second},
+		{header: "MAX-AGE=60", want: time.Minute},
+		{header: "no-cache", want: defaultKeysTTL},
+		{header: "max-age=0", want: defaultKeysTTL},
+		{header: "", want: defaultKeysTTL},
+	}
+	for _, tt := range tests {
+		if got := maxAge(tt.header); got != tt.want {
+			t.Errorf("maxAge(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package idtoken
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/tiny-systems/googleapis-module/pkg/cassette"
+	"github.com/tiny-systems/googleapis-module/pkg/telemetry"
+)
+
+const (
+	// keys are kept this long if the response has no max-age
+	defaultKeysTTL = time.Hour
+	// unknown key IDs refetch keys at most this often, so forged tokens can't flood the endpoint
+	minRefetchInterval = time.Minute
+)
+
+// jwk is a JSON Web Key of a key set
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet is the cached JWKS of a URL
+type keySet struct {
+	// mu guards the keys, they are swapped when a fetch completes
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	expires   time.Time
+	fetchedAt time.Time
+
+	// fetchMu lets one fetch per URL run, concurrent misses wait for its keys
+	fetchMu sync.Mutex
+}
+
+var (
+	keySetsMu sync.Mutex
+	keySets   = make(map[string]*keySet)
+
+	httpClient = &http.Client{
+		Timeout: 30 * time.Second,
+		// Key fetches are traced and measured, and recorded or replayed in cassette mode
+		Transport: cassette.NewTransport(telemetry.NewTransport(http.DefaultTransport)),
+	}
+)
+
+// keySetFor returns the cache entry of a URL
+func keySetFor(url string) *keySet {
+	keySetsMu.Lock()
+	defer keySetsMu.Unlock()
+	set, ok := keySets[url]
+	if !ok {
+		set = &keySet{}
+		keySets[url] = set
+	}
+	return set
+}
+
+// lookup returns a cached key, whether the keys are still fresh and whether they were fetched recently
+func (s *keySet) lookup(kid string, now time.Time) (key crypto.PublicKey, fresh, recent bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fresh = now.Before(s.expires)
+	recent = now.Sub(s.fetchedAt) < minRefetchInterval
+	return s.keys[kid], fresh, recent
+}
+
+// publicKey returns the key kid of the JWKS at url, keys are cached per URL.
+// Fetches hold no lock shared with other URLs, so a slow issuer doesn't stall the others.
+func publicKey(ctx context.Context, url, kid string) (crypto.PublicKey, error) {
+	set := keySetFor(url)
+
+	if key, fresh, _ := set.lookup(kid, time.Now()); key != nil && fresh {
+		return key, nil
+	}
+
+	set.fetchMu.Lock()
+	defer set.fetchMu.Unlock()
+
+	// a fetch we waited for may have brought the key
+	key, fresh, recent := set.lookup(kid, time.Now())
+	if key != nil && fresh {
+		return key, nil
+	}
+	// keys may have rotated, but don't let unknown key IDs flood the endpoint
+	if fresh && recent {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	keys, expires, err := fetchKeySet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	set.mu.Lock()
+	set.keys, set.expires, set.fetchedAt = keys, expires, time.Now()
+	set.mu.Unlock()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeySet downloads a JWKS and returns its supported keys and when they expire
+func fetchKeySet(ctx context.Context, url string) (map[string]crypto.PublicKey, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to fetch signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to read signing keys: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("unable to fetch signing keys: status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			// unsupported keys can't sign tokens we accept
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, time.Now().Add(maxAge(resp.Header.Get("Cache-Control"))), nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		// reject points off the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// maxAge returns the max-age of a Cache-Control header
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultKeysTTL
+}
@@ -0,0 +1,399 @@
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+const (
+	// Security scheme names in generated documents
+	OAuth2Scheme = "oauth2"
+	APIKeyScheme = "apiKey"
+
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+
+	schemaRefPrefix    = "#/components/schemas/"
+	parameterRefPrefix = "#/components/parameters/"
+)
+
+var (
+	pathParamPattern = regexp.MustCompile(`\{\+?([^}]+)\}`)
+	// component keys are limited to ^[a-zA-Z0-9.\-_]+$, e.g. $.xgafv isn't allowed
+	componentKeyPattern = regexp.MustCompile(`[^a-zA-Z0-9.\-_]`)
+)
+
+// Convert turns a discovery document into an OpenAPI 3.1 document.
+// Discovery schemas become components, methods become operations keyed by
+// their path and HTTP method, media upload endpoints get their own operations.
+func Convert(api *googleapismodule.API) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       api.Title,
+			Description: api.Description,
+			Version:     api.Version,
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			Schemas:    make(map[string]*Schema, len(api.Schemas)),
+			Parameters: make(map[string]Parameter, len(api.Parameters)),
+		},
+	}
+	if api.DocumentationLink != "" {
+		doc.ExternalDocs = &ExternalDocs{URL: api.DocumentationLink}
+	}
+	if api.RootUrl != "" {
+		doc.Servers = []Server{{URL: strings.TrimSuffix(api.RootUrl, "/")}}
+	}
+
+	for name, schema := range api.Schemas {
+		doc.Components.Schemas[name] = convertSchema(schema)
+	}
+	for name, param := range api.Parameters {
+		doc.Components.Parameters[componentKey(name)] = convertParameter(name, param)
+	}
+	doc.Components.SecuritySchemes = securitySchemes(api)
+
+	methods := api.GetAllMethods()
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].FullName < methods[j].FullName
+	})
+
+	tags := make(map[string]bool)
+	for _, m := range methods {
+		tag, _, _ := strings.Cut(m.FullName, ".")
+		tags[tag] = true
+
+		path, params := operationPath(api.ServicePath+pathTemplate(m.Method), m.Method)
+		op := operation(api, m.Method, tag, params)
+		addOperation(doc, path, m.Method.HttpMethod, op)
+
+		if upload := uploadOperation(api, m.Method, tag); upload != nil {
+			addOperation(doc, upload.path, m.Method.HttpMethod, upload.op)
+		}
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+
+	return doc
+}
+
+// addOperation adds an operation unless another method already claimed the same path and verb
+func addOperation(doc *Document, path, httpMethod string, op *Operation) {
+	item, ok := doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		doc.Paths[path] = item
+	}
+	verb := strings.ToLower(httpMethod)
+	if _, taken := item[verb]; !taken {
+		item[verb] = op
+	}
+}
+
+// pathTemplate returns the method path, flat path is preferred over reserved
+// expansion like {+name} since OpenAPI path parameters can't contain slashes
+func pathTemplate(method googleapismodule.Method) string {
+	if strings.Contains(method.Path, "{+") && method.FlatPath != "" {
+		return method.FlatPath
+	}
+	return method.Path
+}
+
+// operationPath normalizes a path template and returns its path parameters.
+// Flat path segments unknown to the method are described as plain strings.
+func operationPath(template string, method googleapismodule.Method) (string, []Parameter) {
+	path := "/" + strings.TrimPrefix(template, "/")
+
+	var params []Parameter
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		name := match[1]
+		if p, ok := method.Parameters[name]; ok {
+			param := convertParameter(name, p)
+			param.In, param.Required = "path", true
+			params = append(params, param)
+			continue
+		}
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "path",
+			Description: "Resource name segment",
+			Required:    true,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+	return pathParamPattern.ReplaceAllString(path, "{$1}"), params
+}
+
+func operation(api *googleapismodule.API, method googleapismodule.Method, tag string, pathParams []Parameter) *Operation {
+	op := &Operation{
+		OperationID: method.ID,
+		Description: method.Description,
+		Tags:        []string{tag},
+		Parameters:  append([]Parameter{}, pathParams...),
+		Responses:   make(map[string]Response),
+	}
+
+	for _, name := range queryParameters(method) {
+		op.Parameters = append(op.Parameters, convertParameter(name, method.Parameters[name]))
+	}
+	for _, name := range sortedKeys(api.Parameters) {
+		op.Parameters = append(op.Parameters, Parameter{Ref: parameterRefPrefix + componentKey(name)})
+	}
+
+	if method.Request != nil && method.Request.Ref != "" {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{Ref: schemaRefPrefix + method.Request.Ref}},
+			},
+		}
+	}
+
+	success := Response{Description: "Successful response"}
+	if method.Response != nil && method.Response.Ref != "" {
+		success.Content = map[string]MediaType{
+			"application/json": {Schema: &Schema{Ref: schemaRefPrefix + method.Response.Ref}},
+		}
+	}
+	if method.SupportsMediaDownload {
+		if success.Content == nil {
+			success.Content = make(map[string]MediaType)
+		}
+		success.Content["*/*"] = MediaType{Schema: &Schema{Type: "string", Format: "binary", Description: "Media content, returned with alt=media"}}
+	}
+	op.Responses["200"] = success
+	op.Responses["default"] = Response{Description: "Error response"}
+
+	if len(method.Scopes) > 0 {
+		op.Security = []SecurityRequirement{{OAuth2Scheme: method.Scopes}}
+		if _, ok := api.Parameters["key"]; ok {
+			op.Security = append(op.Security, SecurityRequirement{APIKeyScheme: {}})
+		}
+	}
+	return op
+}
+
+type upload struct {
+	path string
+	op   *Operation
+}
+
+// uploadOperation describes the media upload endpoint of a method, if any
+func uploadOperation(api *googleapismodule.API, method googleapismodule.Method, tag string) *upload {
+	if !method.SupportsMediaUpload || method.MediaUpload == nil {
+		return nil
+	}
+
+	var (
+		path        string
+		multipart   bool
+		uploadTypes []string
+	)
+	for _, name := range sortedKeys(method.MediaUpload.Protocols) {
+		protocol := method.MediaUpload.Protocols[name]
+		if path == "" || name == "simple" {
+			path = protocol.Path
+		}
+		multipart = multipart || protocol.Multipart
+		switch name {
+		case "simple":
+			uploadTypes = append(uploadTypes, "media")
+			if protocol.Multipart {
+				uploadTypes = append(uploadTypes, "multipart")
+			}
+		default:
+			uploadTypes = append(uploadTypes, name)
+		}
+	}
+	if path == "" {
+		return nil
+	}
+
+	fullPath, pathParams := operationPath(path, method)
+	op := operation(api, method, tag, pathParams)
+	op.OperationID = method.ID + ".upload"
+	if method.MediaUpload.MaxSize != "" {
+		op.Description = strings.TrimSpace(op.Description + "\n\nMaximum upload size: " + method.MediaUpload.MaxSize)
+	}
+	op.Parameters = append(op.Parameters, Parameter{
+		Name:     "uploadType",
+		In:       "query",
+		Required: true,
+		Schema:   &Schema{Type: "string", Enum: uploadTypes},
+	})
+
+	content := make(map[string]MediaType)
+	for _, accept := range method.MediaUpload.Accept {
+		content[accept] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+	if multipart {
+		content["multipart/related"] = MediaType{Schema: &Schema{Type: "string", Format: "binary", Description: "Metadata and media parts"}}
+	}
+	op.RequestBody = &RequestBody{Required: true, Content: content}
+
+	return &upload{path: fullPath, op: op}
+}
+
+// queryParameters returns query parameter names, ordered like parameterOrder first
+func queryParameters(method googleapismodule.Method) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string{}, method.ParameterOrder...), sortedKeys(method.Parameters)...) {
+		if p, ok := method.Parameters[name]; ok && p.Location == "query" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func convertParameter(name string, param googleapismodule.Parameter) Parameter {
+	schema := parameterSchema(param)
+	result := Parameter{
+		Name:        name,
+		In:          param.Location,
+		Description: param.Description,
+		Required:    param.Required,
+		Deprecated:  strings.HasPrefix(param.Description, "Deprecated"),
+		Schema:      schema,
+	}
+	if param.Repeated {
+		explode := true
+		result.Explode = &explode
+		result.Schema = &Schema{Type: "array", Items: schema}
+	}
+	return result
+}
+
+func parameterSchema(param googleapismodule.Parameter) *Schema {
+	if param.Type == "array" && param.Items != nil {
+		return &Schema{Type: "array", Items: parameterSchema(*param.Items)}
+	}
+	schema := &Schema{
+		Type:    param.Type,
+		Format:  param.Format,
+		Enum:    param.Enum,
+		Pattern: param.Pattern,
+		Minimum: parseNumber(param.Minimum),
+		Maximum: parseNumber(param.Maximum),
+	}
+	if param.Default != "" {
+		schema.Default = param.Default
+	}
+	if param.Format == "byte" {
+		schema.ContentEncoding = "base64"
+	}
+	return schema
+}
+
+// convertSchema converts a discovery schema keeping references to components
+func convertSchema(gSchema googleapismodule.Schema) *Schema {
+	if gSchema.Ref != "" {
+		return &Schema{
+			Ref:         schemaRefPrefix + gSchema.Ref,
+			Description: gSchema.Description,
+			ReadOnly:    gSchema.ReadOnly,
+		}
+	}
+
+	schema := &Schema{
+		Type:        gSchema.Type,
+		Format:      gSchema.Format,
+		Description: gSchema.Description,
+		Default:     gSchema.Default,
+		Enum:        gSchema.Enum,
+		Pattern:     gSchema.Pattern,
+		ReadOnly:    gSchema.ReadOnly,
+		Minimum:     parseNumber(gSchema.Minimum),
+		Maximum:     parseNumber(gSchema.Maximum),
+	}
+	if gSchema.Type == "any" {
+		schema.Type = ""
+	}
+	if gSchema.Format == "byte" {
+		schema.ContentEncoding = "base64"
+	}
+
+	if len(gSchema.Properties) > 0 {
+		schema.Properties = make(map[string]*Schema, len(gSchema.Properties))
+		for name, prop := range gSchema.Properties {
+			schema.Properties[name] = convertSchema(prop)
+		}
+	}
+	if gSchema.AdditionalProperties != nil {
+		schema.AdditionalProperties = convertSchema(*gSchema.AdditionalProperties)
+	}
+	if gSchema.Items != nil {
+		schema.Items = convertSchema(*gSchema.Items)
+	}
+	return schema
+}
+
+// securitySchemes describes Google OAuth2 and API key authentication
+func securitySchemes(api *googleapismodule.API) map[string]SecurityScheme {
+	schemes := make(map[string]SecurityScheme)
+	if api.Auth != nil && api.Auth.OAuth2 != nil && len(api.Auth.OAuth2.Scopes) > 0 {
+		scopes := make(map[string]string, len(api.Auth.OAuth2.Scopes))
+		for scope, info := range api.Auth.OAuth2.Scopes {
+			scopes[scope] = info.Description
+		}
+		schemes[OAuth2Scheme] = SecurityScheme{
+			Type:        "oauth2",
+			Description: "Google OAuth 2.0",
+			Flows: &OAuthFlows{
+				AuthorizationCode: &OAuthFlow{
+					AuthorizationURL: googleAuthURL,
+					TokenURL:         googleTokenURL,
+					Scopes:           scopes,
+				},
+			},
+		}
+	}
+	if _, ok := api.Parameters["key"]; ok {
+		schemes[APIKeyScheme] = SecurityScheme{
+			Type:        "apiKey",
+			Description: "API key, for public data only",
+			Name:        "key",
+			In:          "query",
+		}
+	}
+	if len(schemes) == 0 {
+		return nil
+	}
+	return schemes
+}
+
+func componentKey(name string) string {
+	return componentKeyPattern.ReplaceAllString(name, "_")
+}
+
+func parseNumber(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+	googleapismodule "github.com/tiny-systems/googleapis-module"
+)
+
+const testDocument = `{
+	"title": "Storage API",
+	"version": "v1",
+	"rootUrl": "https://storage.googleapis.com/",
+	"servicePath": "storage/v1/",
+	"parameters": {
+		"key": {"type": "string", "location": "query"},
+		"$.xgafv": {"type": "string", "location": "query", "enum": ["1", "2"]}
+	},
+	"auth": {"oauth2": {"scopes": {"https://www.googleapis.com/auth/devstorage.read_write": {"description": "Manage your data"}}}},
+	"schemas": {
+		"Object": {"id": "Object", "type": "object", "properties": {
+			"name": {"type": "string"},
+			"size": {"type": "string", "format": "uint64", "readOnly": true},
+			"md5Hash": {"type": "string", "format": "byte"},
+			"metadata": {"type": "object", "additionalProperties": {"type": "string"}},
+			"owner": {"$ref": "Owner", "description": "The owner"},
+			"extra": {"type": "any"}
+		}},
+		"Owner": {"id": "Owner", "type": "object", "properties": {"entity": {"type": "string"}}}
+	},
+	"resources": {
+		"objects": {"methods": {
+			"get": {
+				"id": "storage.objects.get",
+				"path": "b/{bucket}/o/{+object}",
+				"flatPath": "b/{bucket}/o/{objectsId}",
+				"httpMethod": "GET",
+				"parameters": {
+					"bucket": {"type": "string", "location": "path", "required": true},
+					"object": {"type": "string", "location": "path", "required": true},
+					"generation": {"type": "string", "location": "query", "format": "int64"},
+					"fields": {"type": "string", "location": "query", "repeated": true},
+					"maxResults": {"type": "integer", "location": "query", "minimum": "0", "maximum": "1000", "default": "10"}
+				},
+				"parameterOrder": ["bucket", "object", "maxResults"],
+				"response": {"$ref": "Object"},
+				"scopes": ["https://www.googleapis.com/auth/devstorage.read_write"],
+				"supportsMediaDownload": true
+			},
+			"insert": {
+				"id": "storage.objects.insert",
+				"path": "b/{bucket}/o",
+				"httpMethod": "POST",
+				"parameters": {"bucket": {"type": "string", "location": "path", "required": true}},
+				"request": {"$ref": "Object"},
+				"response": {"$ref": "Object"},
+				"supportsMediaUpload": true,
+				"mediaUpload": {
+					"accept": ["*/*"],
+					"maxSize": "5TB",
+					"protocols": {"simple": {"multipart": true, "path": "/upload/storage/v1/b/{bucket}/o"}}
+				}
+			}
+		}}
+	}
+}`
+
+func testAPI(t *testing.T) *googleapismodule.API {
+	t.Helper()
+	var api googleapismodule.API
+	if err := json.Unmarshal([]byte(testDocument), &api); err != nil {
+		t.Fatal(err)
+	}
+	return &api
+}
+
+func TestConvert(t *testing.T) {
+	doc := Convert(testAPI(t))
+
+	if doc.OpenAPI != Version || doc.Info.Title != "Storage API" || doc.Info.Version != "v1" {
+		t.Fatalf("unexpected info %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://storage.googleapis.com" {
+		t.Fatalf("servers %+v", doc.Servers)
+	}
+	if want := []Tag{{Name: "objects"}}; !reflect.DeepEqual(doc.Tags, want) {
+		t.Fatalf("tags %+v, want %+v", doc.Tags, want)
+	}
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	want := map[string]string{
+		// reserved expansion is replaced by the flat path
+		"/storage/v1/b/{bucket}/o/{objectsId}": "get",
+		"/storage/v1/b/{bucket}/o":             "post",
+		"/upload/storage/v1/b/{bucket}/o":      "post",
+	}
+	if len(doc.Paths) != len(want) {
+		t.Fatalf("paths %v, want %v", paths, want)
+	}
+	for path, verb := range want {
+		if doc.Paths[path][verb] == nil {
+			t.Fatalf("missing %s %s in %v", verb, path, paths)
+		}
+	}
+}
+
+func TestConvertOperation(t *testing.T) {
+	doc := Convert(testAPI(t))
+	get := doc.Paths["/storage/v1/b/{bucket}/o/{objectsId}"]["get"]
+	if get.OperationID != "storage.objects.get" {
+		t.Fatalf("operation ID %s", get.OperationID)
+	}
+
+	var names []string
+	for _, p := range get.Parameters {
+		if p.Ref != "" {
+			names = append(names, p.Ref)
+			continue
+		}
+		names = append(names, p.In+":"+p.Name)
+	}
+	wantNames := []string{
+		"path:bucket",
+		"path:objectsId",
+		"query:maxResults",
+		"query:fields",
+		"query:generation",
+		parameterRefPrefix + "_.xgafv",
+		parameterRefPrefix + "key",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("parameters %v, want %v", names, wantNames)
+	}
+
+	segment := get.Parameters[1]
+	if !segment.Required || segment.Schema.Type != "string" {
+		t.Fatalf("flat path segment %+v", segment)
+	}
+	maxResults := get.Parameters[2].Schema
+	if *maxResults.Minimum != 0 || *maxResults.Maximum != 1000 || maxResults.Default != "10" {
+		t.Fatalf("maxResults schema %+v", maxResults)
+	}
+	fields := get.Parameters[3]
+	if fields.Explode == nil || !*fields.Explode || fields.Schema.Type != "array" || fields.Schema.Items.Type != "string" {
+		t.Fatalf("repeated parameter %+v", fields)
+	}
+
+	success := get.Responses["200"]
+	if success.Content["application/json"].Schema.Ref != schemaRefPrefix+"Object" {
+		t.Fatalf("response %+v", success)
+	}
+	if success.Content["*/*"].Schema.Format != "binary" {
+		t.Fatalf("media download is missing from %+v", success)
+	}
+	if _, ok := get.Responses["default"]; !ok {
+		t.Fatal("error response is missing")
+	}
+
+	wantSecurity := []SecurityRequirement{
+		{OAuth2Scheme: {"https://www.googleapis.com/auth/devstorage.read_write"}},
+		{APIKeyScheme: {}},
+	}
+	if !reflect.DeepEqual(get.Security, wantSecurity) {
+		t.Fatalf("security %+v, want %+v", get.Security, wantSecurity)
+	}
+
+	insert := doc.Paths["/storage/v1/b/{bucket}/o"]["post"]
+	if insert.RequestBody == nil || insert.RequestBody.Content["application/json"].Schema.Ref != schemaRefPrefix+"Object" {
+		t.Fatalf("request body %+v", insert.RequestBody)
+	}
+	if insert.Security != nil {
+		t.Fatalf("method without scopes has security %+v", insert.Security)
+	}
+}
+
+func TestConvertUpload(t *testing.T) {
+	doc := Convert(testAPI(t))
+	upload := doc.Paths["/upload/storage/v1/b/{bucket}/o"]["post"]
+
+	if upload.OperationID != "storage.objects.insert.upload" {
+		t.Fatalf("operation ID %s", upload.OperationID)
+	}
+	if upload.Description != "Maximum upload size: 5TB" {
+		t.Fatalf("description %q", upload.Description)
+	}
+	uploadType := upload.Parameters[len(upload.Parameters)-1]
+	if uploadType.Name != "uploadType" || !uploadType.Required || !reflect.DeepEqual(uploadType.Schema.Enum, []string{"media", "multipart"}) {
+		t.Fatalf("upload type %+v", uploadType)
+	}
+	var contentTypes []string
+	for ct := range upload.RequestBody.Content {
+		contentTypes = append(contentTypes, ct)
+	}
+	if len(contentTypes) != 2 || upload.RequestBody.Content["*/*"].Schema == nil || upload.RequestBody.Content["multipart/related"].Schema == nil {
+		t.Fatalf("upload content types %v", contentTypes)
+	}
+}
+
+func TestConvertComponents(t *testing.T) {
+	doc := Convert(testAPI(t))
+
+	object := doc.Components.Schemas["Object"]
+	if object == nil || object.Type != "object" {
+		t.Fatalf("Object schema %+v", object)
+	}
+	props := object.Properties
+	if !props["size"].ReadOnly || props["size"].Format != "uint64" {
+		t.Fatalf("size %+v", props["size"])
+	}
+	if props["md5Hash"].ContentEncoding != "base64" {
+		t.Fatalf("md5Hash %+v", props["md5Hash"])
+	}
+	if props["metadata"].AdditionalProperties == nil || props["metadata"].AdditionalProperties.Type != "string" {
+		t.Fatalf("metadata %+v", props["metadata"])
+	}
+	if props["owner"].Ref != schemaRefPrefix+"Owner" || props["owner"].Description != "The owner" {
+		t.Fatalf("owner %+v", props["owner"])
+	}
+	if props["extra"].Type != "" {
+		t.Fatalf("any type is kept as %q", props["extra"].Type)
+	}
+
+	// component keys can't contain $
+	if _, ok := doc.Components.Parameters["_.xgafv"]; !ok {
+		t.Fatalf("parameters %v", doc.Components.Parameters)
+	}
+
+	oauth := doc.Components.SecuritySchemes[OAuth2Scheme]
+	if oauth.Flows == nil || oauth.Flows.AuthorizationCode.Scopes["https://www.googleapis.com/auth/devstorage.read_write"] != "Manage your data" {
+		t.Fatalf("oauth2 scheme %+v", oauth)
+	}
+	if key := doc.Components.SecuritySchemes[APIKeyScheme]; key.In != "query" || key.Name != "key" {
+		t.Fatalf("api key scheme %+v", key)
+	}
+}
+
+func TestConvertWithoutAuth(t *testing.T) {
+	doc := Convert(&googleapismodule.API{Title: "Empty", Version: "v1"})
+	if doc.Components.SecuritySchemes != nil || len(doc.Paths) != 0 || doc.Servers != nil {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+}
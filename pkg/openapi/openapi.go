@@ -0,0 +1,130 @@
+package openapi
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Document is an OpenAPI 3.1 document, limited to what discovery documents can express
+type Document struct {
+	OpenAPI      string                `json:"openapi"`
+	Info         Info                  `json:"info"`
+	ExternalDocs *ExternalDocs         `json:"externalDocs,omitempty"`
+	Servers      []Server              `json:"servers,omitempty"`
+	Tags         []Tag                 `json:"tags,omitempty"`
+	Paths        map[string]PathItem   `json:"paths"`
+	Components   Components            `json:"components"`
+	Security     []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// ExternalDocs links the API documentation
+type ExternalDocs struct {
+	URL string `json:"url"`
+}
+
+// Server is the root URL of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations by top-level discovery resource
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps lowercase HTTP methods to operations
+type PathItem map[string]*Operation
+
+// Operation is a single discovery method
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Parameter is either an inline parameter or a reference to components
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Deprecated  bool    `json:"deprecated,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes the method request
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a method response
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a content type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is a JSON Schema 2020-12 subset
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Components holds reusable schemas, parameters and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	Parameters      map[string]Parameter      `json:"parameters,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how requests are authorized
+type SecurityScheme struct {
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	In          string      `json:"in,omitempty"`
+	Flows       *OAuthFlows `json:"flows,omitempty"`
+}
+
+// OAuthFlows lists supported OAuth2 flows
+type OAuthFlows struct {
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow describes an OAuth2 flow and its scopes
+type OAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl"`
+	TokenURL         string            `json:"tokenUrl"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// SecurityRequirement maps scheme names to required scopes
+type SecurityRequirement map[string][]string
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	ModeQueue  = "queue"
+	ModeReject = "reject"
+
+	ScopePrincipal = "principal"
+	ScopeProject   = "project"
+
+	defaultRequests = 100
+	defaultInterval = 100
+	defaultMaxWait  = 30
+
+	// limiters not used for this long are dropped
+	idleTimeout   = time.Hour
+	sweepInterval = 10 * time.Minute
+)
+
+// Settings configures client-side rate limiting of Google API calls.
+// Limits are shared by all components of the module process calling the same service.
+type Settings struct {
+	Enabled  bool   `json:"enabled" title:"Enable Rate Limit" description:"Limit calls made to the Google API from this module"`
+	Requests int    `json:"requests" title:"Requests" default:"100" minimum:"1" description:"Number of requests allowed per interval"`
+	Interval int    `json:"interval" title:"Interval" default:"100" minimum:"1" description:"Interval in seconds, e.g. 100 for Calendar's queries per 100 seconds quota"`
+	Burst    int    `json:"burst,omitempty" title:"Burst" minimum:"0" description:"Requests allowed at once. Defaults to the number of requests per interval"`
+	Scope    string `json:"scope" title:"Scope" default:"principal" enum:"principal,project" enumTitles:"Per principal,Per project" description:"Per principal budgets each user or service account separately, per project shares one budget between everyone calling with credentials of the same Google Cloud project"`
+	Mode     string `json:"mode" title:"Mode" default:"queue" enum:"queue,reject" enumTitles:"Queue,Reject" description:"Queue waits for budget to free up, reject fails immediately"`
+	MaxWait  int    `json:"maxWait,omitempty" title:"Max Wait" default:"30" minimum:"0" description:"Seconds a queued request may wait before it is rejected"`
+}
+
+// Error is returned when a request is over budget
+type Error struct {
+	Service    string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s: budget available in %s", e.Service, e.RetryAfter.Round(time.Millisecond))
+}
+
+type limiterKey struct {
+	service string
+	scope   string
+	key     string
+}
+
+type limiter struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[limiterKey]*limiter)
+	lastSweep  time.Time
+)
+
+// Wait blocks until the request fits into the budget of the service and key, which identifies
+// the principal or, with ScopeProject, the Google Cloud project of the call.
+// Returns *Error if the request is rejected.
+func Wait(ctx context.Context, service, key string, settings Settings) error {
+	if !settings.Enabled {
+		return nil
+	}
+	if key == "" && settings.Scope == ScopeProject {
+		return fmt.Errorf("rate limit per project needs the project of the call, set the project ID of the client config")
+	}
+
+	lim := get(service, key, settings)
+
+	if settings.Mode == ModeReject {
+		r := lim.Reserve()
+		if delay := r.Delay(); delay > 0 {
+			r.Cancel()
+			return &Error{Service: service, RetryAfter: delay}
+		}
+		return nil
+	}
+
+	maxWait := settings.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultMaxWait
+	}
+
+	r := lim.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if delay > time.Duration(maxWait)*time.Second {
+		r.Cancel()
+		return &Error{Service: service, RetryAfter: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// get returns the shared limiter for the service and key, updating its limits if settings changed
+func get(service, key string, settings Settings) *rate.Limiter {
+	requests := settings.Requests
+	if requests <= 0 {
+		requests = defaultRequests
+	}
+	interval := settings.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	burst := settings.Burst
+	if burst <= 0 {
+		burst = requests
+	}
+	limit := rate.Limit(float64(requests) / float64(interval))
+
+	scope := settings.Scope
+	if scope == "" {
+		scope = ScopePrincipal
+	}
+	// principals and projects never share a budget
+	lk := limiterKey{service: service, scope: scope, key: key}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	now := time.Now()
+	sweep(now)
+
+	l, ok := limiters[lk]
+	if !ok {
+		l = &limiter{Limiter: rate.NewLimiter(limit, burst)}
+		limiters[lk] = l
+	}
+	l.lastUsed = now
+
+	if l.Limit() != limit {
+		l.SetLimitAt(now, limit)
+	}
+	if l.Burst() != burst {
+		l.SetBurstAt(now, burst)
+	}
+	return l.Limiter
+}
+
+// sweep drops idle limiters, must be called with lock held
+func sweep(now time.Time) {
+	if now.Sub(lastSweep) < sweepInterval {
+		return
+	}
+	lastSweep = now
+	for key, l := range limiters {
+		if now.Sub(l.lastUsed) > idleTimeout {
+			delete(limiters, key)
+		}
+	}
+}
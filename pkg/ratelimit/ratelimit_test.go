@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitReject(t *testing.T) {
+	settings := Settings{Enabled: true, Requests: 2, Interval: 60, Mode: ModeReject}
+
+	for i := 0; i < 2; i++ {
+		if err := Wait(context.Background(), "reject:v1", "alice", settings); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	var limitErr *Error
+	if err := Wait(context.Background(), "reject:v1", "alice", settings); !errors.As(err, &limitErr) {
+		t.Fatalf("expected *Error over budget, got %v", err)
+	}
+	if limitErr.RetryAfter <= 0 || limitErr.Service != "reject:v1" {
+		t.Fatalf("unexpected error %+v", limitErr)
+	}
+
+	// budgets are per principal and service
+	if err := Wait(context.Background(), "reject:v1", "bob", settings); err != nil {
+		t.Fatalf("other principal: %v", err)
+	}
+	if err := Wait(context.Background(), "other:v1", "alice", settings); err != nil {
+		t.Fatalf("other service: %v", err)
+	}
+	// disabled limits don't count
+	if err := Wait(context.Background(), "reject:v1", "alice", Settings{}); err != nil {
+		t.Fatalf("disabled: %v", err)
+	}
+}
+
+func TestWaitProjectScope(t *testing.T) {
+	settings := Settings{Enabled: true, Requests: 1, Interval: 60, Mode: ModeReject, Scope: ScopeProject}
+
+	if err := Wait(context.Background(), "project:v1", "project-a", settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := Wait(context.Background(), "project:v1", "project-a", settings); err == nil {
+		t.Fatal("calls of the same project don't share the budget")
+	}
+	if err := Wait(context.Background(), "project:v1", "project-b", settings); err != nil {
+		t.Fatalf("other project: %v", err)
+	}
+	// a principal named like a project has its own budget
+	principal := settings
+	principal.Scope = ScopePrincipal
+	if err := Wait(context.Background(), "project:v1", "project-a", principal); err != nil {
+		t.Fatalf("principal: %v", err)
+	}
+
+	if err := Wait(context.Background(), "project:v1", "", settings); err == nil {
+		t.Fatal("expected an error without project")
+	}
+}
+
+func TestWaitQueue(t *testing.T) {
+	// 10 requests per second, one at once
+	settings := Settings{Enabled: true, Requests: 10, Interval: 1, Burst: 1, Mode: ModeQueue, MaxWait: 1}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := Wait(context.Background(), "queue:v1", "alice", settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("queued requests took %s, want about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, "queue:v1", "alice", settings); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// waits longer than max wait are rejected
+	slow := Settings{Enabled: true, Requests: 1, Interval: 60, Mode: ModeQueue, MaxWait: 1}
+	if err := Wait(context.Background(), "slow:v1", "alice", slow); err != nil {
+		t.Fatal(err)
+	}
+	var limitErr *Error
+	if err := Wait(context.Background(), "slow:v1", "alice", slow); !errors.As(err, &limitErr) {
+		t.Fatalf("expected *Error beyond max wait, got %v", err)
+	}
+}
+
+func TestGetUpdatesLimits(t *testing.T) {
+	settings := Settings{Enabled: true, Requests: 10, Interval: 10}
+	l := get("update:v1", "alice", settings)
+	if l.Limit() != 1 || l.Burst() != 10 {
+		t.Fatalf("limit %v burst %d, want 1 and 10", l.Limit(), l.Burst())
+	}
+
+	settings.Requests, settings.Burst = 20, 5
+	if get("update:v1", "alice", settings) != l {
+		t.Fatal("changed settings replaced the limiter")
+	}
+	if l.Limit() != 2 || l.Burst() != 5 {
+		t.Fatalf("limit %v burst %d, want 2 and 5", l.Limit(), l.Burst())
+	}
+}
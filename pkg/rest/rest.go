@@ -0,0 +1,99 @@
+// Package rest is the runtime used by generated components to call Google REST APIs
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// Error is returned for non-2xx responses
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Expand substitutes path parameters, {name} values are escaped while
+// reserved expansion {+name} keeps slashes, e.g. for projects/p/databases/d
+func Expand(template string, params map[string]string) string {
+	for name, value := range params {
+		template = strings.ReplaceAll(template, "{"+name+"}", url.PathEscape(value))
+		template = strings.ReplaceAll(template, "{+"+name+"}", value)
+	}
+	return template
+}
+
+// AddQuery adds a query parameter, zero values are skipped and slices add one value per element
+func AddQuery(query url.Values, name string, value any) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.IsZero() {
+		return
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			query.Add(name, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return
+	}
+	query.Set(name, fmt.Sprint(v.Interface()))
+}
+
+// Do sends a JSON request and decodes the JSON response into out, body and out may be nil
+func Do(ctx context.Context, client *http.Client, httpMethod, rawURL string, query url.Values, body, out any) error {
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil && !reflect.ValueOf(body).IsZero() {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, rawURL, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if bodyReader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Error{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
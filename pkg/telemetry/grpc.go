@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"path"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClientOption instruments gRPC based Google clients (e.g. Firestore) with spans and metrics
+func GRPCClientOption(service string) option.ClientOption {
+	return option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(service)))
+}
+
+// GRPCStreamClientOption instruments streaming gRPC calls such as Firestore listeners
+func GRPCStreamClientOption(service string) option.ClientOption {
+	return option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(StreamClientInterceptor(service)))
+}
+
+// UnaryClientInterceptor creates a span and records metrics for every unary call
+func UnaryClientInterceptor(service string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, fullMethod string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span, attrs := startGRPC(ctx, service, fullMethod)
+		start := time.Now()
+
+		err := invoker(ctx, fullMethod, req, reply, cc, opts...)
+		finishGRPC(ctx, span, attrs, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor creates a span covering the whole stream lifetime
+func StreamClientInterceptor(service string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, fullMethod string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span, attrs := startGRPC(ctx, service, fullMethod)
+		start := time.Now()
+
+		stream, err := streamer(ctx, desc, cc, fullMethod, opts...)
+		if err != nil {
+			finishGRPC(ctx, span, attrs, start, err)
+			return nil, err
+		}
+		return &instrumentedStream{ClientStream: stream, finish: func(err error) {
+			finishGRPC(ctx, span, attrs, start, err)
+		}}, nil
+	}
+}
+
+func startGRPC(ctx context.Context, service, fullMethod string) (context.Context, trace.Span, []attribute.KeyValue) {
+	attrs := []attribute.KeyValue{
+		ServiceKey.String(service),
+		MethodKey.String(path.Base(fullMethod)),
+	}
+	ctx, span := tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(attribute.String("rpc.system", "grpc")),
+	)
+	return ctx, span, attrs
+}
+
+func finishGRPC(ctx context.Context, span trace.Span, attrs []attribute.KeyValue, start time.Time, err error) {
+	code := status.Code(err)
+	attrs = append(attrs, StatusKey.String(code.String()))
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+
+	var reason string
+	if err != nil && code != codes.Canceled {
+		reason = code.String()
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+	record(ctx, start, attrs, reason, 0, 0)
+}
+
+// instrumentedStream ends the span when the stream finishes
+type instrumentedStream struct {
+	grpc.ClientStream
+	finished bool
+	finish   func(err error)
+}
+
+func (s *instrumentedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.finished {
+		s.finished = true
+		if err == io.EOF {
+			s.finish(nil)
+		} else {
+			s.finish(err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxErrorBody limits how much of an error response is inspected for the error reason
+const maxErrorBody = 64 << 10
+
+// SensitiveParams are query and form parameters whose values never end up in spans or recordings
+var SensitiveParams = []string{"key", "access_token", "oauth_token", "refresh_token", "client_secret", "code", "assertion", "subject_token"}
+
+// Transport instruments outgoing HTTP requests with spans and metrics
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with tracing and metrics, http.DefaultTransport is used if base is nil
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+
+	service, method := serviceFromHost(req.URL.Host), req.Method
+	retries := 0
+	if c := callFrom(ctx); c != nil {
+		service, method = c.service, c.method
+		retries = c.attempt(req.Method + " " + req.URL.String())
+	}
+
+	attrs := []attribute.KeyValue{
+		ServiceKey.String(service),
+		MethodKey.String(method),
+	}
+
+	ctx, span := tracer().Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			HTTPMethodKey.String(req.Method),
+			URLKey.String(RedactURL(req.URL)),
+			RetryCountKey.Int(retries),
+		),
+	)
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	sent := req.ContentLength
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		record(ctx, start, append(attrs, StatusKey.String("transport_error")), "transport_error", sent, 0)
+		return nil, err
+	}
+
+	status := strconv.Itoa(resp.StatusCode)
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	attrs = append(attrs, StatusKey.String(status))
+
+	var reason string
+	if resp.StatusCode >= 400 {
+		reason = errorReason(resp)
+		span.SetAttributes(ReasonKey.String(reason))
+		span.SetStatus(codes.Error, status+" "+reason)
+	}
+
+	finish := func(received int64) {
+		span.SetAttributes(attribute.Int64("http.response.body.size", received))
+		span.End()
+		record(ctx, start, attrs, reason, sent, received)
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		finish(0)
+		return resp, nil
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, done: finish}
+	return resp, nil
+}
+
+// countingBody counts received bytes and finishes instrumentation once the body is consumed or closed
+type countingBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(received int64)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.n) })
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.once.Do(func() { b.done(b.n) })
+	return b.ReadCloser.Close()
+}
+
+// errorReason extracts the reason of a Google API error response, leaving the body readable
+func errorReason(resp *http.Response) string {
+	fallback := strings.ReplaceAll(strings.ToLower(http.StatusText(resp.StatusCode)), " ", "_")
+	if resp.Body == nil {
+		return fallback
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	if err != nil {
+		return fallback
+	}
+
+	var apiErr struct {
+		Error struct {
+			Status string `json:"status"`
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(head, &apiErr) != nil {
+		return fallback
+	}
+	if len(apiErr.Error.Errors) > 0 && apiErr.Error.Errors[0].Reason != "" {
+		return apiErr.Error.Errors[0].Reason
+	}
+	if apiErr.Error.Status != "" {
+		return apiErr.Error.Status
+	}
+	return fallback
+}
+
+// RedactURL returns the URL with credentials removed from query parameters
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	for _, name := range SensitiveParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// serviceFromHost guesses the service from hosts like calendar.googleapis.com
+func serviceFromHost(host string) string {
+	return strings.TrimSuffix(host, ".googleapis.com")
+}
@@ -0,0 +1,113 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/tiny-systems/googleapis-module"
+
+// Attribute keys shared by spans and metrics
+const (
+	ServiceKey    = attribute.Key("googleapis.service")
+	MethodKey     = attribute.Key("googleapis.method")
+	StatusKey     = attribute.Key("googleapis.status")
+	ReasonKey     = attribute.Key("googleapis.error.reason")
+	RetryCountKey = attribute.Key("googleapis.retry_count")
+	URLKey        = attribute.Key("url.full")
+	HTTPMethodKey = attribute.Key("http.request.method")
+)
+
+type instruments struct {
+	duration      metric.Float64Histogram
+	errors        metric.Int64Counter
+	requestBytes  metric.Int64Counter
+	responseBytes metric.Int64Counter
+}
+
+var (
+	instrumentsOnce sync.Once
+	inst            instruments
+)
+
+// meters returns metric instruments, created on first use so a meter provider
+// configured by the module SDK at startup is picked up
+func meters() instruments {
+	instrumentsOnce.Do(func() {
+		meter := otel.Meter(instrumentationName)
+		inst.duration, _ = meter.Float64Histogram("googleapis.client.duration",
+			metric.WithDescription("Duration of outgoing Google API calls"),
+			metric.WithUnit("s"))
+		inst.errors, _ = meter.Int64Counter("googleapis.client.errors",
+			metric.WithDescription("Failed outgoing Google API calls by reason"))
+		inst.requestBytes, _ = meter.Int64Counter("googleapis.client.request.size",
+			metric.WithDescription("Bytes sent to Google APIs"),
+			metric.WithUnit("By"))
+		inst.responseBytes, _ = meter.Int64Counter("googleapis.client.response.size",
+			metric.WithDescription("Bytes received from Google APIs"),
+			metric.WithUnit("By"))
+	})
+	return inst
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// call describes the logical API call outgoing requests belong to
+type call struct {
+	service string
+	method  string
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// attempt returns how many times the same request was sent before within the call
+func (c *call) attempt(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.attempts[key]
+	c.attempts[key] = n + 1
+	return n
+}
+
+type callKey struct{}
+
+// WithCall annotates the context with the service and method being called,
+// so spans and metrics of outgoing requests carry them and retries are counted
+func WithCall(ctx context.Context, service, method string) context.Context {
+	return context.WithValue(ctx, callKey{}, &call{
+		service:  service,
+		method:   method,
+		attempts: make(map[string]int),
+	})
+}
+
+func callFrom(ctx context.Context) *call {
+	c, _ := ctx.Value(callKey{}).(*call)
+	return c
+}
+
+// record reports metrics of a finished request
+func record(ctx context.Context, start time.Time, attrs []attribute.KeyValue, reason string, sent, received int64) {
+	m := meters()
+	set := metric.WithAttributes(attrs...)
+
+	m.duration.Record(ctx, time.Since(start).Seconds(), set)
+	if sent > 0 {
+		m.requestBytes.Add(ctx, sent, set)
+	}
+	if received > 0 {
+		m.responseBytes.Add(ctx, received, set)
+	}
+	if reason != "" {
+		m.errors.Add(ctx, 1, metric.WithAttributes(append(attrs, ReasonKey.String(reason))...))
+	}
+}